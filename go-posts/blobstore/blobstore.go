@@ -0,0 +1,45 @@
+// Package blobstore stores the binary content of attachments.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/charmbracelet/log"
+)
+
+// ErrNotFound is returned by BlobStore.Get when there is no blob under the key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore is a flat key-value store of blobs. Keys are opaque strings made of
+// letters, digits, '-', '_', '.' and '/'.
+type BlobStore interface {
+	Put(ctx context.Context, key string, content io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blobs, keys without a blob are ignored.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// New creates the blob store selected by the BLOB_BACKEND ENV. Only "local"
+// (the default) is implemented, it keeps the blobs under BLOB_DIR, which
+// has to be shared by all replicas.
+func New() BlobStore {
+	switch backend := os.Getenv("BLOB_BACKEND"); backend {
+	case "", "local":
+		dir := os.Getenv("BLOB_DIR")
+		if dir == "" {
+			dir = "./data/blobs"
+		}
+
+		store, err := NewLocalStore(dir)
+		if err != nil {
+			log.Fatal("Unable to create blob store", "dir", dir, "err", err)
+		}
+		return store
+	default:
+		log.Fatal("Unknown BLOB_BACKEND", "backend", backend)
+		return nil
+	}
+}
@@ -2,29 +2,102 @@ package cache
 
 import (
 	"context"
-	"log"
+	"errors"
+	"go-posts/utils"
 	"os"
+	"time"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/charmbracelet/log"
+	"golang.org/x/sync/singleflight"
 )
 
-type RedisCache struct {
-	Client *redis.Client
+// ErrMiss is returned by Cache.Get when the key is not cached.
+var ErrMiss = errors.New("cache miss")
+
+// Cache is a key-value cache with expiring entries. Entries can also be
+// grouped under tags: TagKey turns a key into its version under the current
+// generation of the tag, and InvalidateTags moves tags to a new generation,
+// which makes every entry stored under their previous keys unreachable.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	// GetMany returns the values of the cached keys, missing keys are absent
+	// from the result.
+	GetMany(ctx context.Context, keys []string) (map[string][]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	// Increment increments the counter stored under key and returns its new
+	// value. A new counter expires ttl after its creation.
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
+	TagKey(ctx context.Context, tag string, key string) (string, error)
+	InvalidateTags(ctx context.Context, tags ...string) error
 }
 
-func (c *RedisCache) ConnectCache() {
-	addr := os.Getenv("CACHE_ADDR")
+// Backend is a cache implementation, which also stores the home timelines
+// and carries the events between the replicas.
+type Backend interface {
+	Cache
+	Timelines
+	Events
+}
 
-	opt, err := redis.ParseURL(addr)
-	if err != nil {
-		log.Fatal("Incorrect CACHE_ADDR URL provided")
+// New creates the backend selected by the CACHE_BACKEND ENV: "redis" (the
+// default, connecting to CACHE_ADDR) or "memory" for an in-process LRU cache
+// of CACHE_MEMORY_SIZE entries, which works without Redis but is not shared
+// between replicas, events included.
+func New() Backend {
+	switch backend := os.Getenv("CACHE_BACKEND"); backend {
+	case "", "redis":
+		c := &RedisCache{}
+		c.ConnectCache()
+		return c
+	case "memory":
+		log.Info("Using in-memory cache")
+		return NewMemoryCache(utils.GetEnvInt("CACHE_MEMORY_SIZE", 10000))
+	default:
+		log.Fatal("Unknown CACHE_BACKEND", "backend", backend)
+		return nil
 	}
+}
 
-	client := redis.NewClient(opt)
+var group singleflight.Group
 
-	if status := client.Ping(context.Background()); status.Err() != nil {
-		log.Fatal("Connection Refused", status.Err())
+// Remember returns the value cached under key, computing and caching it with
+// load on a miss. Concurrent misses of the same key within this process share
+// a single load call. If the cache is unavailable the value is loaded anyway.
+func Remember(ctx context.Context, c Cache, key string, ttl time.Duration, load func() ([]byte, error)) ([]byte, error) {
+	value, err := c.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if !errors.Is(err, ErrMiss) {
+		log.Error("Unable to get data from cache", "key", key, "err", err)
+		return load()
 	}
 
-	c.Client = client
+	res, err, _ := group.Do(key, func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := c.Set(context.Background(), key, value, ttl); err != nil {
+			log.Error("Unable to set data to cache", "key", key, "err", err)
+		}
+		return value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]byte), nil
+}
+
+// RememberTagged is Remember for a key under the current generation of tag.
+func RememberTagged(ctx context.Context, c Cache, tag string, key string, ttl time.Duration, load func() ([]byte, error)) ([]byte, error) {
+	taggedKey, err := c.TagKey(ctx, tag, key)
+	if err != nil {
+		log.Error("Unable to get tag generation", "tag", tag, "err", err)
+		return load()
+	}
+	return Remember(ctx, c, taggedKey, ttl, load)
 }
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+)
+
+// eventsBuffer is how many messages a subscription holds for a slow reader.
+const eventsBuffer = 64
+
+// Events delivers messages published by any replica to the subscribers of
+// every replica. Delivery is best effort: messages published while nobody
+// listens, or while a subscriber lags behind, are lost.
+type Events interface {
+	Publish(ctx context.Context, channel string, message []byte) error
+	// Subscribe delivers the messages published to the channel until ctx is
+	// done, then closes the returned channel.
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+}
+
+func (c *RedisCache) Publish(ctx context.Context, channel string, message []byte) error {
+	return c.Client.Publish(ctx, channel, message).Err()
+}
+
+func (c *RedisCache) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	pubsub := c.Client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	messages := make(chan []byte, eventsBuffer)
+	go func() {
+		defer close(messages)
+		defer pubsub.Close()
+
+		received := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-received:
+				if !ok {
+					return
+				}
+				select {
+				case messages <- []byte(msg.Payload):
+				default:
+				}
+			}
+		}
+	}()
+	return messages, nil
+}
@@ -0,0 +1,281 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryCache is an in-process LRU cache holding at most size entries. Tag
+// generations and timelines are kept outside of the LRU, so they are never
+// evicted.
+type MemoryCache struct {
+	mu          sync.Mutex
+	size        int
+	entries     map[string]*list.Element
+	order       *list.List
+	generations map[string]int64
+	timelines   map[uint][]timelineItem
+	subscribers map[string]map[chan []byte]bool
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+type timelineItem struct {
+	postID uint
+	score  int64
+}
+
+func NewMemoryCache(size int) *MemoryCache {
+	if size < 1 {
+		size = 1
+	}
+	return &MemoryCache{
+		size:        size,
+		entries:     make(map[string]*list.Element),
+		order:       list.New(),
+		generations: make(map[string]int64),
+		timelines:   make(map[uint][]timelineItem),
+		subscribers: make(map[string]map[chan []byte]bool),
+	}
+}
+
+// lookup returns the live entry of the key, dropping it if it has expired.
+// The caller must hold the lock.
+func (c *MemoryCache) lookup(key string) *memoryEntry {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil
+	}
+
+	c.order.MoveToFront(elem)
+	return entry
+}
+
+// store saves the value, evicting the least recently used entries if the
+// cache is full. The caller must hold the lock.
+func (c *MemoryCache) store(key string, value []byte, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.lookup(key)
+	if entry == nil {
+		return nil, ErrMiss
+	}
+	return append([]byte(nil), entry.value...), nil
+}
+
+func (c *MemoryCache) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if entry := c.lookup(key); entry != nil {
+			res[key] = append([]byte(nil), entry.value...)
+		}
+	}
+	return res, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store(key, append([]byte(nil), value...), ttl)
+	return nil
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := c.entries[key]; ok {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.lookup(key)
+	if entry == nil {
+		c.store(key, []byte("1"), ttl)
+		return 1, nil
+	}
+
+	n, err := strconv.ParseInt(string(entry.value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value of %v is not a counter", key)
+	}
+	n++
+	entry.value = []byte(strconv.FormatInt(n, 10))
+	return n, nil
+}
+
+func (c *MemoryCache) TagKey(ctx context.Context, tag string, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return fmt.Sprintf("%s:g%d:%s", tag, c.generations[tag], key), nil
+}
+
+func (c *MemoryCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		c.generations[tag]++
+	}
+	return nil
+}
+
+func (c *MemoryCache) PushToTimelines(ctx context.Context, userIDs []uint, postID uint, createdAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item := timelineItem{postID: postID, score: createdAt.UnixMicro()}
+	for _, userID := range userIDs {
+		timeline, ok := c.timelines[userID]
+		if !ok {
+			continue
+		}
+		i := sort.Search(len(timeline), func(i int) bool { return timeline[i].score < item.score })
+		timeline = append(timeline, timelineItem{})
+		copy(timeline[i+1:], timeline[i:])
+		timeline[i] = item
+		if len(timeline) > TimelineLength {
+			timeline = timeline[:TimelineLength]
+		}
+		c.timelines[userID] = timeline
+	}
+	return nil
+}
+
+func (c *MemoryCache) FillTimeline(ctx context.Context, userID uint, entries []TimelineEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timeline := make([]timelineItem, 0, len(entries))
+	for _, entry := range entries {
+		timeline = append(timeline, timelineItem{postID: entry.PostID, score: entry.CreatedAt.UnixMicro()})
+	}
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].score > timeline[j].score })
+	if len(timeline) > TimelineLength {
+		timeline = timeline[:TimelineLength]
+	}
+	c.timelines[userID] = timeline
+	return nil
+}
+
+func (c *MemoryCache) DropTimeline(ctx context.Context, userID uint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.timelines, userID)
+	return nil
+}
+
+func (c *MemoryCache) HasTimeline(ctx context.Context, userID uint) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.timelines[userID]
+	return ok, nil
+}
+
+func (c *MemoryCache) GetTimeline(ctx context.Context, userID uint, before time.Time, offset int, limit int) ([]uint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := []uint{}
+	for _, item := range c.timelines[userID] {
+		if len(ids) == limit {
+			break
+		}
+		if before.IsZero() || item.score <= before.UnixMicro() {
+			if offset > 0 {
+				offset--
+				continue
+			}
+			ids = append(ids, item.postID)
+		}
+	}
+	return ids, nil
+}
+
+func (c *MemoryCache) Publish(ctx context.Context, channel string, message []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for subscriber := range c.subscribers[channel] {
+		select {
+		case subscriber <- message:
+		default:
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	messages := make(chan []byte, eventsBuffer)
+	if c.subscribers[channel] == nil {
+		c.subscribers[channel] = make(map[chan []byte]bool)
+	}
+	c.subscribers[channel][messages] = true
+
+	go func() {
+		<-ctx.Done()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subscribers[channel], messages)
+		close(messages)
+	}()
+	return messages, nil
+}
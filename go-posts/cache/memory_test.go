@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2)
+
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+	// reading a makes b the least recently used entry
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatalf("Get(a) = %v", err)
+	}
+	c.Set(ctx, "c", []byte("3"), 0)
+
+	tests := []struct {
+		key  string
+		miss bool
+	}{
+		{key: "a"},
+		{key: "b", miss: true},
+		{key: "c"},
+	}
+	for _, tt := range tests {
+		_, err := c.Get(ctx, tt.key)
+		if miss := errors.Is(err, ErrMiss); miss != tt.miss {
+			t.Errorf("Get(%v) miss = %v, want %v", tt.key, miss, tt.miss)
+		}
+	}
+}
+
+func TestMemoryCacheExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+
+	c.Set(ctx, "short", []byte("1"), 10*time.Millisecond)
+	c.Set(ctx, "long", []byte("2"), time.Hour)
+	c.Set(ctx, "forever", []byte("3"), 0)
+	time.Sleep(20 * time.Millisecond)
+
+	values, err := c.GetMany(ctx, []string{"short", "long", "forever"})
+	if err != nil {
+		t.Fatalf("GetMany() = %v", err)
+	}
+	if _, ok := values["short"]; ok {
+		t.Error("expired entry is still cached")
+	}
+	if string(values["long"]) != "2" || string(values["forever"]) != "3" {
+		t.Errorf("GetMany() = %q, want the unexpired entries", values)
+	}
+}
+
+func TestMemoryCacheIncrement(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+
+	for want := int64(1); want <= 3; want++ {
+		n, err := c.Increment(ctx, "counter", time.Hour)
+		if err != nil || n != want {
+			t.Fatalf("Increment() = %v, %v, want %v", n, err, want)
+		}
+	}
+
+	c.Set(ctx, "text", []byte("abc"), 0)
+	if _, err := c.Increment(ctx, "text", time.Hour); err == nil {
+		t.Error("Increment() of a non counter succeeded")
+	}
+}
+
+func TestRememberTaggedInvalidation(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+
+	loads := 0
+	load := func() ([]byte, error) {
+		loads++
+		return []byte{byte('0' + loads)}, nil
+	}
+	remember := func(tag string) string {
+		value, err := RememberTagged(ctx, c, tag, "key", time.Hour, load)
+		if err != nil {
+			t.Fatalf("RememberTagged(%v) = %v", tag, err)
+		}
+		return string(value)
+	}
+
+	tests := []struct {
+		name       string
+		invalidate []string
+		tag        string
+		want       string
+	}{
+		{name: "first read loads", tag: "feed", want: "1"},
+		{name: "second read is cached", tag: "feed", want: "1"},
+		{name: "other tag loads separately", tag: "other", want: "2"},
+		{name: "invalidating another tag keeps the entry", invalidate: []string{"other"}, tag: "feed", want: "1"},
+		{name: "invalidating the tag reloads", invalidate: []string{"feed"}, tag: "feed", want: "3"},
+		{name: "the new generation is cached", tag: "feed", want: "3"},
+	}
+	for _, tt := range tests {
+		if len(tt.invalidate) > 0 {
+			if err := c.InvalidateTags(ctx, tt.invalidate...); err != nil {
+				t.Fatalf("%v: InvalidateTags() = %v", tt.name, err)
+			}
+		}
+		if got := remember(tt.tag); got != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRememberDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+
+	failure := errors.New("database is down")
+	if _, err := Remember(ctx, c, "key", time.Hour, func() ([]byte, error) { return nil, failure }); !errors.Is(err, failure) {
+		t.Fatalf("Remember() = %v, want %v", err, failure)
+	}
+
+	value, err := Remember(ctx, c, "key", time.Hour, func() ([]byte, error) { return []byte("ok"), nil })
+	if err != nil || string(value) != "ok" {
+		t.Errorf("Remember() = %q, %v after a failed load", value, err)
+	}
+}
+
+func TestMemoryTimelines(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10)
+	base := time.Now()
+
+	// pushes skip timelines which were never filled
+	c.PushToTimelines(ctx, []uint{1}, 10, base)
+	if has, _ := c.HasTimeline(ctx, 1); has {
+		t.Fatal("PushToTimelines() created a timeline")
+	}
+
+	c.FillTimeline(ctx, 1, nil)
+	if has, _ := c.HasTimeline(ctx, 1); !has {
+		t.Fatal("an empty filled timeline does not exist")
+	}
+
+	c.FillTimeline(ctx, 1, []TimelineEntry{
+		{PostID: 1, CreatedAt: base.Add(1 * time.Second)},
+		{PostID: 3, CreatedAt: base.Add(3 * time.Second)},
+	})
+	c.PushToTimelines(ctx, []uint{1}, 2, base.Add(2*time.Second))
+	c.PushToTimelines(ctx, []uint{1}, 4, base.Add(4*time.Second))
+
+	tests := []struct {
+		name   string
+		before time.Time
+		offset int
+		limit  int
+		want   []uint
+	}{
+		{name: "newest first", limit: 10, want: []uint{4, 3, 2, 1}},
+		{name: "limited", limit: 2, want: []uint{4, 3}},
+		{name: "with offset", offset: 2, limit: 2, want: []uint{2, 1}},
+		{name: "before is inclusive", before: base.Add(3 * time.Second), limit: 10, want: []uint{3, 2, 1}},
+		{name: "before with offset", before: base.Add(3 * time.Second), offset: 1, limit: 1, want: []uint{2}},
+		{name: "run out", offset: 4, limit: 2, want: []uint{}},
+	}
+	for _, tt := range tests {
+		got, err := c.GetTimeline(ctx, 1, tt.before, tt.offset, tt.limit)
+		if err != nil {
+			t.Fatalf("%v: GetTimeline() = %v", tt.name, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+
+	c.DropTimeline(ctx, 1)
+	if has, _ := c.HasTimeline(ctx, 1); has {
+		t.Error("DropTimeline() kept the timeline")
+	}
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisCache struct {
+	Client *redis.Client
+}
+
+func (c *RedisCache) ConnectCache() {
+	addr := os.Getenv("CACHE_ADDR")
+
+	opt, err := redis.ParseURL(addr)
+	if err != nil {
+		log.Fatal("Incorrect CACHE_ADDR URL provided")
+	}
+
+	client := redis.NewClient(opt)
+
+	if status := client.Ping(context.Background()); status.Err() != nil {
+		log.Fatal("Connection Refused", status.Err())
+	}
+
+	c.Client = client
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.Client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	return value, err
+}
+
+func (c *RedisCache) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	res := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return res, nil
+	}
+
+	values, err := c.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		if s, ok := value.(string); ok {
+			res[keys[i]] = []byte(s)
+		}
+	}
+	return res, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.Client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.Client.Del(ctx, keys...).Err()
+}
+
+func (c *RedisCache) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	n, err := c.Client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 && ttl > 0 {
+		if err := c.Client.Expire(ctx, key, ttl).Err(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func generationKey(tag string) string {
+	return "gen:" + tag
+}
+
+func (c *RedisCache) TagKey(ctx context.Context, tag string, key string) (string, error) {
+	generation, err := c.Client.Get(ctx, generationKey(tag)).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	return fmt.Sprintf("%s:g%d:%s", tag, generation, key), nil
+}
+
+func (c *RedisCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	_, err := c.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			pipe.Incr(ctx, generationKey(tag))
+		}
+		return nil
+	})
+	return err
+}
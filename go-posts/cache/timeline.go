@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Timelines stores the materialised home timelines of users: the ids of the
+// posts pushed to them, ordered by the post creation time. A timeline is
+// built from the database by FillTimeline, afterwards new posts are pushed
+// into it.
+type Timelines interface {
+	// PushToTimelines adds the post to the home timelines of the given users.
+	// Users without a materialised timeline are skipped, their timeline gets
+	// the post when it is filled.
+	PushToTimelines(ctx context.Context, userIDs []uint, postID uint, createdAt time.Time) error
+	// FillTimeline replaces the user's home timeline with the given entries.
+	// The timeline exists afterwards even if there are no entries.
+	FillTimeline(ctx context.Context, userID uint, entries []TimelineEntry) error
+	// DropTimeline removes the user's home timeline, so that it is filled
+	// again on the next read.
+	DropTimeline(ctx context.Context, userID uint) error
+	// HasTimeline reports whether the user's home timeline is materialised. A
+	// missing timeline (never built or expired) has to be filled first.
+	HasTimeline(ctx context.Context, userID uint) (bool, error)
+	// GetTimeline returns up to limit post ids of the user's home timeline
+	// created at or before the given moment, newest first, skipping the first
+	// offset of them. A zero moment starts from the newest post. Fewer than
+	// limit ids mean the timeline has run out.
+	GetTimeline(ctx context.Context, userID uint, before time.Time, offset int, limit int) ([]uint, error)
+}
+
+// TimelineEntry is a post of a home timeline.
+type TimelineEntry struct {
+	PostID    uint
+	CreatedAt time.Time
+}
+
+// Redis home timelines are sorted sets of post ids scored by the post creation
+// time in microseconds. Only the newest TimelineLength entries are kept. The
+// timelineMarker member, scored 0 and never returned, keeps a filled but empty
+// timeline existing.
+const (
+	TimelineLength = 800
+	timelineTTL    = 7 * 24 * time.Hour
+	timelineMarker = "0"
+)
+
+// pushToTimelines adds a post to the timelines which exist, KEYS are the
+// timelines and ARGV the score, the post id, the length and the TTL.
+var pushToTimelines = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+	if redis.call("EXISTS", key) == 1 then
+		redis.call("ZADD", key, ARGV[1], ARGV[2])
+		redis.call("ZREMRANGEBYRANK", key, 0, -tonumber(ARGV[3]) - 1)
+		redis.call("EXPIRE", key, ARGV[4])
+	end
+end
+return 0
+`)
+
+func timelineKey(userID uint) string {
+	return fmt.Sprintf("timeline:%d", userID)
+}
+
+func (c *RedisCache) PushToTimelines(ctx context.Context, userIDs []uint, postID uint, createdAt time.Time) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		keys[i] = timelineKey(userID)
+	}
+	return pushToTimelines.Run(ctx, c.Client, keys, createdAt.UnixMicro(), postID, TimelineLength, int(timelineTTL.Seconds())).Err()
+}
+
+func (c *RedisCache) FillTimeline(ctx context.Context, userID uint, entries []TimelineEntry) error {
+	members := []redis.Z{{Score: 0, Member: timelineMarker}}
+	for _, entry := range entries {
+		members = append(members, redis.Z{Score: float64(entry.CreatedAt.UnixMicro()), Member: entry.PostID})
+	}
+
+	key := timelineKey(userID)
+	_, err := c.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.ZAdd(ctx, key, members...)
+		pipe.ZRemRangeByRank(ctx, key, 0, -TimelineLength-1)
+		pipe.Expire(ctx, key, timelineTTL)
+		return nil
+	})
+	return err
+}
+
+func (c *RedisCache) DropTimeline(ctx context.Context, userID uint) error {
+	return c.Client.Del(ctx, timelineKey(userID)).Err()
+}
+
+func (c *RedisCache) HasTimeline(ctx context.Context, userID uint) (bool, error) {
+	n, err := c.Client.Exists(ctx, timelineKey(userID)).Result()
+	return n > 0, err
+}
+
+func (c *RedisCache) GetTimeline(ctx context.Context, userID uint, before time.Time, offset int, limit int) ([]uint, error) {
+	max := "+inf"
+	if !before.IsZero() {
+		max = strconv.FormatInt(before.UnixMicro(), 10)
+	}
+
+	members, err := c.Client.ZRevRangeByScore(ctx, timelineKey(userID), &redis.ZRangeBy{
+		Min:    "-inf",
+		Max:    max,
+		Offset: int64(offset),
+		Count:  int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseUint(member, 10, 64)
+		if err != nil || member == timelineMarker {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
@@ -1,27 +1,24 @@
 package main
 
 import (
+	"go-posts/blobstore"
 	"go-posts/cache"
 	"go-posts/server"
 	"go-posts/storage"
 	"go-posts/utils"
+	"os"
 
 	"github.com/charmbracelet/log"
 )
 
-func init() {
-	utils.CheckENVS()
-}
-
 func setupService() *server.Server {
-	store := &storage.PostgreStore{}
-	store.CreateStorage()
-	store.Migrate()
+	store := storage.New()
+
+	cache := cache.New()
 
-	cache := &cache.RedisCache{}
-	cache.ConnectCache()
+	blobs := blobstore.New()
 
-	server := server.CreateService(store, cache)
+	server := server.CreateService(store, cache, blobs)
 	server.SetupRoutes()
 
 	return server
@@ -29,9 +26,19 @@ func setupService() *server.Server {
 
 func main() {
 	log.SetLevel(log.DebugLevel)
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	utils.CheckENVS()
 	log.Info("Starting the Go-Posts...")
 
 	server := setupService()
+	go server.RunTrashPurger()
+	go server.RunScheduler()
+	go server.RunStream()
 
 	server.Run(5000)
 }
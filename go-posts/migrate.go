@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"go-posts/storage"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+const migrateUsage = "Usage: go-posts migrate up|down|status"
+
+// runMigrate implements the migrate subcommand. "up" applies the pending
+// migrations, "down" rolls back the latest one and "status" lists them all.
+func runMigrate(args []string) {
+	if len(args) != 1 {
+		log.Fatal(migrateUsage)
+	}
+	if os.Getenv("DB_ADDR") == "" {
+		log.Fatal("DB_ADDR ENV is not set")
+	}
+
+	store := &storage.PostgreStore{}
+	store.CreateStorage()
+
+	migrator, err := store.Migrator()
+	if err != nil {
+		log.Fatal("Unable to load migrations", "err", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal("Unable to apply migrations", "err", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		migration, err := migrator.Down()
+		if err != nil {
+			log.Fatal("Unable to roll back migration", "err", err)
+		}
+		if migration == nil {
+			fmt.Println("no applied migrations")
+			return
+		}
+		fmt.Printf("rolled back %04d_%s\n", migration.Version, migration.Name)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal("Unable to read migrations status", "err", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
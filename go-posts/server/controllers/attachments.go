@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-posts/blobstore"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"go-posts/utils"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// Limits of the uploaded files. Images are also limited in pixels, since a
+// small compressed file can decode into a huge image.
+var (
+	attachmentMaxSize   = int64(utils.GetEnvInt("ATTACHMENT_MAX_SIZE", 5<<20))
+	attachmentMaxPixels = utils.GetEnvInt("ATTACHMENT_MAX_PIXELS", 25_000_000)
+)
+
+const thumbnailSide = 320
+
+// attachmentTypes are the accepted content types, as sniffed from the file
+// content, mapped to whether they are images.
+var attachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"application/pdf": false,
+}
+
+// AttachmentView is an attachment as returned to clients, with the URLs of
+// its content.
+type AttachmentView struct {
+	models.Attachment
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+func newAttachmentView(attachment models.Attachment) AttachmentView {
+	view := AttachmentView{
+		Attachment: attachment,
+		URL:        fmt.Sprintf("/posts/attachments/%d", attachment.ID),
+	}
+	if attachment.ThumbnailKey != "" {
+		view.ThumbnailURL = view.URL + "/thumbnail"
+	}
+	return view
+}
+
+func newBlobKey(uploaderID uint) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("attachments/%d/%s", uploaderID, hex.EncodeToString(id)), nil
+}
+
+// UploadAttachment stores the multipart "file" of the caller. The returned
+// attachment can then be attached to a new post through its id.
+func UploadAttachment(store storage.Storage, blobs blobstore.BlobStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		// leaves room for the multipart envelope around the file
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, attachmentMaxSize+64<<10)
+
+		header, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"Error": "File is too large"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"Error": "A multipart file named \"file\" is required"})
+			return
+		}
+		if header.Size > attachmentMaxSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"Error": "File is too large"})
+			return
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			log.Error("Unable to open uploaded file", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"Error": "Internal server error"})
+			return
+		}
+		content, err := io.ReadAll(io.LimitReader(file, attachmentMaxSize+1))
+		file.Close()
+		if err != nil {
+			log.Error("Unable to read uploaded file", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"Error": "Internal server error"})
+			return
+		}
+
+		contentType := http.DetectContentType(content)
+		isImage, allowed := attachmentTypes[contentType]
+		if !allowed {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"Error": "Unsupported file type " + contentType})
+			return
+		}
+
+		attachment := &models.Attachment{
+			UploaderID:  user.User_Id,
+			ContentType: contentType,
+			Size:        int64(len(content)),
+		}
+
+		var thumbnail bytes.Buffer
+		if isImage {
+			config, _, err := image.DecodeConfig(bytes.NewReader(content))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid image"})
+				return
+			}
+			if config.Width*config.Height > attachmentMaxPixels {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"Error": "Image has too many pixels"})
+				return
+			}
+
+			img, _, err := image.Decode(bytes.NewReader(content))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid image"})
+				return
+			}
+			attachment.Width, attachment.Height = config.Width, config.Height
+
+			if err := jpeg.Encode(&thumbnail, utils.Thumbnail(img, thumbnailSide), &jpeg.Options{Quality: 80}); err != nil {
+				log.Error("Unable to encode thumbnail", "err", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"Error": "Internal server error"})
+				return
+			}
+		}
+
+		key, err := newBlobKey(user.User_Id)
+		if err != nil {
+			log.Error("Unable to generate blob key", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"Error": "Internal server error"})
+			return
+		}
+
+		attachment.BlobKey = key
+		keys := []string{key}
+		if isImage {
+			attachment.ThumbnailKey = key + "-thumb.jpg"
+			keys = append(keys, attachment.ThumbnailKey)
+		}
+
+		err = blobs.Put(c, attachment.BlobKey, bytes.NewReader(content))
+		if err == nil && isImage {
+			err = blobs.Put(c, attachment.ThumbnailKey, &thumbnail)
+		}
+		if err == nil {
+			err = store.CreateAttachment(attachment)
+		}
+		if err != nil {
+			if err := blobs.Delete(c, keys...); err != nil {
+				log.Error("Unable to delete blobs of failed upload", "keys", keys, "err", err)
+			}
+			log.Error("Unable to store attachment", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"Error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, newAttachmentView(*attachment))
+	}
+}
+
+// GetAttachment returns the content of an attachment, or of its thumbnail if
+// thumbnail is set. Attachments of unpublished posts are only served to their
+// uploader.
+func GetAttachment(store storage.Storage, blobs blobstore.BlobStore, thumbnail bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid attachment id"})
+			return
+		}
+
+		attachment, err := store.GetAttachment(uint(id))
+		if err != nil {
+			storageError(c, err, "Attachment not found")
+			return
+		}
+
+		public := false
+		if attachment.PostID != nil {
+			post, err := store.GetPost(*attachment.PostID)
+			public = err == nil && post.Status == models.PostPublished
+		}
+		if !public {
+			viewer := optionalUser(c)
+			if viewer == nil || viewer.User_Id != attachment.UploaderID {
+				c.JSON(http.StatusNotFound, gin.H{"Error": "Attachment not found"})
+				return
+			}
+		}
+
+		key, contentType, size := attachment.BlobKey, attachment.ContentType, attachment.Size
+		if thumbnail {
+			if attachment.ThumbnailKey == "" {
+				c.JSON(http.StatusNotFound, gin.H{"Error": "Attachment has no thumbnail"})
+				return
+			}
+			key, contentType, size = attachment.ThumbnailKey, "image/jpeg", -1
+		}
+
+		content, err := blobs.Get(c, key)
+		if errors.Is(err, blobstore.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"Error": "Attachment not found"})
+			return
+		}
+		if err != nil {
+			log.Error("Unable to read attachment", "key", key, "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"Error": "Internal server error"})
+			return
+		}
+		defer content.Close()
+
+		cacheControl := "private, max-age=3600"
+		if public {
+			cacheControl = "public, max-age=86400"
+		}
+		c.DataFromReader(http.StatusOK, size, contentType, content, map[string]string{
+			"Cache-Control":          cacheControl,
+			"X-Content-Type-Options": "nosniff",
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-posts/cache"
+	"go-posts/users"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// authorTTL bounds how long a profile change can go unnoticed if go-users
+// fails to invalidate the cached author.
+const authorTTL = 10 * time.Minute
+
+func authorKey(userID uint) string {
+	return fmt.Sprintf("author:%d", userID)
+}
+
+// resolveAuthors returns the authors of the given user ids, reading them from
+// the cache and resolving the misses with a single go-users request. Authors
+// that can not be resolved are missing from the result.
+func resolveAuthors(ctx context.Context, backend cache.Cache, userIDs []uint) map[uint]*users.Author {
+	authors := make(map[uint]*users.Author, len(userIDs))
+	if len(userIDs) == 0 {
+		return authors
+	}
+
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = authorKey(id)
+	}
+
+	cached, err := backend.GetMany(ctx, keys)
+	if err != nil {
+		log.Error("Unable to get authors from cache", "err", err)
+	}
+
+	missing := []uint{}
+	for _, id := range userIDs {
+		var author users.Author
+		if data, ok := cached[authorKey(id)]; ok && json.Unmarshal(data, &author) == nil {
+			authors[id] = &author
+			continue
+		}
+		missing = append(missing, id)
+	}
+
+	if len(missing) == 0 {
+		return authors
+	}
+
+	resolved, err := users.GetAuthors(missing)
+	if err != nil {
+		log.Error("Unable to resolve authors", "err", err)
+		return authors
+	}
+
+	for i := range resolved {
+		author := resolved[i]
+		authors[author.ID] = &author
+
+		data, _ := json.Marshal(author)
+		if err := backend.Set(ctx, authorKey(author.ID), data, authorTTL); err != nil {
+			log.Error("Unable to set author to cache", "err", err)
+		}
+	}
+
+	return authors
+}
+
+type InvalidateAuthorDto struct {
+	User_id uint `form:"id" binding:"required,min=1"`
+}
+
+// InvalidateAuthor is called by go-users when a profile changes, behind
+// middleware.RequireInternal.
+func InvalidateAuthor(cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req InvalidateAuthorDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		if err := cache.Delete(c, authorKey(req.User_id)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"Error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"Message": "Success"})
+	}
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"go-posts/cache"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+type BookmarkPostDto struct {
+	PostID uint `form:"post_id" binding:"required"`
+}
+
+func BookmarkPost(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BookmarkPostDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.BookmarkPost()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		if err := store.BookmarkPost(user.User_Id, req.PostID); err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"Message": "Success"})
+	}
+}
+
+type UnbookmarkPostDto struct {
+	PostID uint `form:"post_id" binding:"required"`
+}
+
+func UnbookmarkPost(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UnbookmarkPostDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.UnbookmarkPost()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		if err := store.UnbookmarkPost(user.User_Id, req.PostID); err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"Message": "Success"})
+	}
+}
+
+type GetBookmarkedPostsDto struct {
+	PageID   uint `form:"pageid" binding:"required,min=1"`
+	PageSize uint `form:"pagesize" binding:"required,min=1"`
+}
+
+// GetBookmarkedPosts returns the caller's bookmarks, most recently saved
+// first. Bookmarks are private, so there is no way to ask for another user's.
+func GetBookmarkedPosts(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GetBookmarkedPostsDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.GetBookmarkedPosts()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		posts, err := store.GetBookmarkedPosts(int(req.PageSize), int((req.PageID-1)*req.PageSize), user.User_Id)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+
+		views, err := buildPostViews(c, store, cache, posts, user)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+		c.JSON(http.StatusOK, views)
+	}
+}
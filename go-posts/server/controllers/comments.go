@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"errors"
+	"go-posts/cache"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// CommentView is a comment with its replies nested under it.
+type CommentView struct {
+	models.Comment
+	Replies []*CommentView `json:"replies"`
+}
+
+// buildCommentTrees nests the comments returned by Storage.GetCommentThreads
+// under their parents, keeping the storage order on every level.
+func buildCommentTrees(comments []models.Comment) []*CommentView {
+	nodes := make(map[uint]*CommentView, len(comments))
+	for _, comment := range comments {
+		nodes[comment.ID] = &CommentView{Comment: comment, Replies: []*CommentView{}}
+	}
+
+	roots := []*CommentView{}
+	for _, comment := range comments {
+		node := nodes[comment.ID]
+		if comment.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[*comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+	return roots
+}
+
+type CreateCommentDto struct {
+	PostID   uint   `json:"post_id" binding:"required"`
+	ParentID *uint  `json:"parent_id"`
+	Body     string `json:"body" binding:"required,min=1,max=350"`
+}
+
+func CreateComment(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateCommentDto
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error("Unable to bind json: handlers.CreateComment()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+		if !canPost(c, user) {
+			return
+		}
+
+		comment := &models.Comment{
+			PostID:   req.PostID,
+			ParentID: req.ParentID,
+			AuthorID: user.User_Id,
+			Body:     req.Body,
+		}
+
+		err := store.CreateComment(comment)
+		switch {
+		case errors.Is(err, storage.ErrCommentTooDeep), errors.Is(err, storage.ErrParentMismatch):
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		case err != nil:
+			storageError(c, err, "Post or parent comment not found")
+			return
+		}
+
+		notifyCommented(store, *comment)
+
+		c.JSON(http.StatusOK, comment)
+	}
+}
+
+type GetCommentsDto struct {
+	PostID   uint `form:"post_id" binding:"required"`
+	PageID   uint `form:"pageid" binding:"required,min=1"`
+	PageSize uint `form:"pagesize" binding:"required,min=1"`
+}
+
+// GetComments returns a page of comment trees of the post. Pages are counted
+// in top level comments, every tree is returned whole.
+func GetComments(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GetCommentsDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.GetComments()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		// comments are as visible as the post itself: trashed, draft and
+		// scheduled posts only to their author
+		post, err := store.GetPost(req.PostID)
+		if errors.Is(err, storage.ErrNotFound) {
+			post, err = store.GetDeletedPost(req.PostID)
+		}
+		if err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+		if post.Status != models.PostPublished || post.DeletedAt.Valid {
+			viewer := optionalUser(c)
+			if viewer == nil || viewer.User_Id != post.AuthorID {
+				c.JSON(http.StatusNotFound, gin.H{"Error": "Post not found"})
+				return
+			}
+		}
+
+		comments, err := store.GetCommentThreads(int(req.PageSize), int((req.PageID-1)*req.PageSize), req.PostID)
+		if err != nil {
+			storageError(c, err, "Comments not found")
+			return
+		}
+
+		c.JSON(http.StatusOK, buildCommentTrees(comments))
+	}
+}
+
+type EditCommentDto struct {
+	CommentID uint   `json:"comment_id" binding:"required"`
+	Body      string `json:"body" binding:"required,min=1,max=350"`
+}
+
+func EditComment(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req EditCommentDto
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error("Unable to bind json: handlers.EditComment()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		comment, err := store.GetComment(req.CommentID)
+		if err != nil {
+			storageError(c, err, "Comment not found")
+			return
+		}
+
+		if comment.AuthorID != user.User_Id {
+			c.JSON(http.StatusForbidden, gin.H{"Error": "Unable to edit other user's comments"})
+			return
+		}
+
+		if err := store.UpdateComment(req.CommentID, req.Body); err != nil {
+			storageError(c, err, "Comment not found")
+			return
+		}
+
+		comment.Body = req.Body
+		c.JSON(http.StatusOK, comment)
+	}
+}
+
+type DeleteCommentDto struct {
+	CommentID uint `form:"comment_id" binding:"required"`
+}
+
+func DeleteComment(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req DeleteCommentDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.DeleteComment()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		comment, err := store.GetComment(req.CommentID)
+		if err != nil {
+			storageError(c, err, "Comment not found")
+			return
+		}
+
+		if comment.AuthorID != user.User_Id {
+			c.JSON(http.StatusForbidden, gin.H{"Error": "Unable to delete other user's comments"})
+			return
+		}
+
+		if err := store.DeleteComment(req.CommentID); err != nil {
+			storageError(c, err, "Comment not found")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"Message": "Success"})
+	}
+}
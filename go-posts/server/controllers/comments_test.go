@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"fmt"
+	"go-posts/cache"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetCommentsHidesInvisiblePosts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	engine := gin.New()
+	engine.GET("/comments", GetComments(store, cache.NewMemoryCache(10)))
+
+	create := func(status string) uint {
+		post := models.Post{AuthorID: 1, Title: "title", Body: "body", Status: status}
+		if err := store.CreatePost(&post, nil); err != nil {
+			t.Fatalf("CreatePost() = %v", err)
+		}
+		return post.ID
+	}
+	published := create(models.PostPublished)
+	draft := create(models.PostDraft)
+	trashed := create(models.PostPublished)
+	store.DeletePost(trashed)
+
+	tests := []struct {
+		name   string
+		postID uint
+		want   int
+	}{
+		{name: "published", postID: published, want: http.StatusOK},
+		{name: "draft", postID: draft, want: http.StatusNotFound},
+		{name: "trashed", postID: trashed, want: http.StatusNotFound},
+		{name: "missing", postID: 1000, want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/comments?post_id=%d&pageid=1&pagesize=10", tt.postID), nil)
+		res := httptest.NewRecorder()
+		engine.ServeHTTP(res, req)
+		if res.Code != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.name, res.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"time"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// FeedQuery holds the pagination parameters shared by the post feeds. Old
+// clients page with pageid/pagesize, new ones pass limit and the next_cursor
+// of the previous response.
+type FeedQuery struct {
+	PageID   int    `form:"pageid" binding:"omitempty,min=1"`
+	PageSize int    `form:"pagesize" binding:"omitempty,min=1"`
+	Cursor   string `form:"cursor"`
+	Limit    int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// CursorMode reports whether the request uses keyset pagination.
+func (q *FeedQuery) CursorMode() bool {
+	return q.Limit > 0 || q.Cursor != ""
+}
+
+// Validate checks that either page or cursor parameters are complete.
+func (q *FeedQuery) Validate() error {
+	if q.CursorMode() {
+		if q.Limit == 0 {
+			return errors.New("limit is required when using cursor")
+		}
+		return nil
+	}
+	if q.PageID == 0 || q.PageSize == 0 {
+		return errors.New("either pageid and pagesize or limit are required")
+	}
+	return nil
+}
+
+func (q *FeedQuery) Offset() int {
+	return (q.PageID - 1) * q.PageSize
+}
+
+// CursorPage is the response of a feed in cursor mode. NextCursor is empty on
+// the last page.
+type CursorPage struct {
+	Posts      []PostView `json:"posts"`
+	NextCursor string     `json:"next_cursor"`
+}
+
+type cursorPayload struct {
+	Feed       string    `json:"f"`
+	CreatedAt  time.Time `json:"t,omitempty"`
+	LikesCount uint      `json:"l,omitempty"`
+	Rank       float32   `json:"r,omitempty"`
+	ID         uint      `json:"i"`
+}
+
+// encodeCursor builds the opaque cursor for the given position. The feed
+// name is embedded so that a cursor cannot be replayed on another feed.
+func encodeCursor(feed string, position storage.PostCursor) string {
+	data, _ := json.Marshal(cursorPayload{
+		Feed:       feed,
+		CreatedAt:  position.CreatedAt,
+		LikesCount: position.LikesCount,
+		Rank:       position.Rank,
+		ID:         position.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor issued by encodeCursor for the same feed. An
+// empty cursor means the first page and decodes to nil.
+func decodeCursor(feed string, cursor string) (*storage.PostCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Feed != feed || payload.ID == 0 {
+		return nil, errInvalidCursor
+	}
+
+	return &storage.PostCursor{
+		CreatedAt:  payload.CreatedAt,
+		LikesCount: payload.LikesCount,
+		Rank:       payload.Rank,
+		ID:         payload.ID,
+	}, nil
+}
+
+// trimCursorPage turns the result of a storage query made with limit+1 into
+// at most limit posts and the cursor of the next page, if there is one.
+func trimCursorPage(feed string, posts []models.Post, limit int) ([]models.Post, string) {
+	if len(posts) <= limit {
+		return posts, ""
+	}
+	posts = posts[:limit]
+	last := posts[len(posts)-1]
+	return posts, encodeCursor(feed, storage.PostCursor{CreatedAt: last.CreatedAt, LikesCount: last.LikesCount, ID: last.ID})
+}
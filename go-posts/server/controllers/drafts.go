@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-posts/cache"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// publication validates the requested status of a post, which defaults to
+// published, and the publication time, which only scheduled posts have.
+func publication(status string, publishAt *time.Time) (string, *time.Time, error) {
+	switch status {
+	case "", models.PostPublished:
+		return models.PostPublished, nil, nil
+	case models.PostDraft:
+		return models.PostDraft, nil, nil
+	case models.PostScheduled:
+		if publishAt == nil || !publishAt.After(time.Now()) {
+			return "", nil, errors.New("scheduled posts need a publish_at in the future")
+		}
+		return models.PostScheduled, publishAt, nil
+	default:
+		return "", nil, errors.New("status must be one of draft, scheduled or published")
+	}
+}
+
+// PublishedPosts does what follows the publication of posts: their hashtags
+// are indexed, they are pushed into the followers' timelines, the mentioned
+// users are notified, the cached feeds are invalidated and the posts are
+// streamed to the connected clients.
+func PublishedPosts(ctx context.Context, store storage.Storage, backend cache.Cache, timelines cache.Timelines, events cache.Events, posts ...models.Post) {
+	if len(posts) == 0 {
+		return
+	}
+
+	for _, post := range posts {
+		updatePostTags(store, post.ID, post.Body)
+		fanOutPost(timelines, post)
+		notifyMentions(store, post)
+	}
+	bumpFeeds(ctx, backend, postFeeds...)
+
+	views, err := buildPostViews(ctx, store, backend, posts, nil)
+	if err != nil {
+		log.Error("Unable to build the published posts", "err", err)
+		return
+	}
+	for _, view := range views {
+		publishStreamEvent(ctx, events, streamPost, view.ID, view)
+	}
+}
+
+type GetDraftsDto struct {
+	PageID   uint `form:"pageid" binding:"required,min=1"`
+	PageSize uint `form:"pagesize" binding:"required,min=1"`
+}
+
+// GetDrafts returns the caller's drafts and scheduled posts, most recently
+// updated first.
+func GetDrafts(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GetDraftsDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.GetDrafts()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		posts, err := store.GetDrafts(int(req.PageSize), int((req.PageID-1)*req.PageSize), user.User_Id)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+
+		c.JSON(http.StatusOK, posts)
+	}
+}
+
+type EditDraftDto struct {
+	PostID    uint       `json:"post_id" binding:"required"`
+	Title     string     `json:"title" binding:"required,min=2,max=50"`
+	Body      string     `json:"body" binding:"required,min=2,max=350"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// EditDraft replaces the content of an unpublished post and its publication
+// settings: it can stay a draft, be scheduled or be published right away.
+func EditDraft(store storage.Storage, cache cache.Cache, timelines cache.Timelines, events cache.Events) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req EditDraftDto
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error("Unable to bind json: handlers.EditDraft()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		status, publishAt, err := publication(req.Status, req.PublishAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		post, err := store.GetPost(req.PostID)
+		if err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+		if post.AuthorID != user.User_Id {
+			c.JSON(http.StatusForbidden, gin.H{"Error": "Unable to edit other user's posts"})
+			return
+		}
+		if post.Status == models.PostPublished {
+			c.JSON(http.StatusConflict, gin.H{"Error": "Post is already published"})
+			return
+		}
+
+		post, err = store.UpdateDraft(req.PostID, req.Title, req.Body, status, publishAt)
+		if err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+
+		if post.Status == models.PostPublished {
+			PublishedPosts(c, store, cache, timelines, events, post)
+		}
+
+		c.JSON(http.StatusOK, post)
+	}
+}
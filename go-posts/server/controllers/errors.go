@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"errors"
+	"go-posts/storage"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// storageError responds to a failed storage call: missing records are
+// reported as 404 with the notFound message, conflicts as 409 and anything
+// else is logged and reported as 500 without leaking the cause.
+func storageError(c *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"Error": notFound})
+	case errors.Is(err, storage.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"Error": err.Error()})
+	default:
+		log.Error("Storage call failed", "path", c.FullPath(), "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"Error": "Internal server error"})
+	}
+}
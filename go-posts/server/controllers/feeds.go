@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"go-posts/cache"
+	"go-posts/storage/models"
+	"go-posts/utils"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// How long a feed page and the results of a popular search query are cached.
+var (
+	feedTTL   = utils.GetEnvDuration("CACHE_FEED_TTL", 60*time.Second)
+	searchTTL = utils.GetEnvDuration("CACHE_SEARCH_TTL", 60*time.Second)
+)
+
+// Names of the cached feeds, used as cache tags and to tag cursors.
+const (
+	feedLatest    = "latest"
+	feedMostLiked = "mostliked"
+	feedSearch    = "search"
+	feedTrending  = "trending"
+)
+
+// Feeds affected by a change of a post's content or existence.
+var postFeeds = []string{feedLatest, feedMostLiked, feedSearch, feedTrending}
+
+// bumpFeeds invalidates the cached pages of the feeds. Stale pages expire on
+// their own, so a failure is only logged.
+func bumpFeeds(ctx context.Context, backend cache.Cache, feeds ...string) {
+	if err := backend.InvalidateTags(ctx, feeds...); err != nil {
+		log.Error("Unable to invalidate cached feeds", "feeds", feeds, "err", err)
+	}
+}
+
+// loadFeed returns a cached page of the feed, loading it on a miss.
+func loadFeed(ctx context.Context, backend cache.Cache, feed string, page string, load func() ([]byte, error)) ([]byte, error) {
+	return cache.RememberTagged(ctx, backend, feed, page, feedTTL, load)
+}
+
+// loadFeedPage returns a cached page of posts, loading it from the storage on
+// a miss.
+func loadFeedPage(ctx context.Context, backend cache.Cache, feed string, page string, load func() ([]models.Post, error)) ([]models.Post, error) {
+	data, err := loadFeed(ctx, backend, feed, page, func() ([]byte, error) {
+		log.Debug("Result was not found in cache, getting from the database...", "feed", feed, "page", page)
+		posts, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(posts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []models.Post
+	err = json.Unmarshal(data, &posts)
+	return posts, err
+}
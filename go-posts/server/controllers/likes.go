@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"go-posts/cache"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+type LikePostDto struct {
+	PostID uint `form:"post_id" binding:"required"`
+}
+
+func LikePost(store storage.Storage, cache cache.Cache, events cache.Events) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LikePostDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.LikePost()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		if err := store.LikePost(user.User_Id, req.PostID); err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+
+		if post, err := store.GetPost(req.PostID); err != nil {
+			log.Error("Unable to get the liked post", "post_id", req.PostID, "err", err)
+		} else {
+			notify(store, models.Notification{
+				UserID:  post.AuthorID,
+				ActorID: user.User_Id,
+				Type:    models.NotificationLike,
+				PostID:  &post.ID,
+			})
+			publishLikes(c, events, post)
+		}
+
+		bumpFeeds(c, cache, feedMostLiked)
+
+		c.JSON(http.StatusOK, gin.H{"Message": "Success"})
+	}
+}
+
+type UnlikePostDto struct {
+	PostID uint `form:"post_id" binding:"required"`
+}
+
+func UnlikePost(store storage.Storage, cache cache.Cache, events cache.Events) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UnlikePostDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.UnlikePost()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		if err := store.UnlikePost(user.User_Id, req.PostID); err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+
+		if post, err := store.GetPost(req.PostID); err != nil {
+			log.Error("Unable to get the unliked post", "post_id", req.PostID, "err", err)
+		} else {
+			publishLikes(c, events, post)
+		}
+
+		bumpFeeds(c, cache, feedMostLiked)
+
+		c.JSON(http.StatusOK, gin.H{"Message": "Success"})
+	}
+}
+
+type GetLikedPostsDto struct {
+	PageID   uint `form:"pageid" binding:"required,min=1"`
+	PageSize uint `form:"pagesize" binding:"required,min=1"`
+}
+
+func GetLikedPosts(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GetLikedPostsDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.GetLikedPosts()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		posts, err := store.GetLikedPosts(int(req.PageSize), int((req.PageID-1)*req.PageSize), user.User_Id)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+
+		views, err := buildPostViews(c, store, cache, posts, user)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+		c.JSON(http.StatusOK, views)
+	}
+}
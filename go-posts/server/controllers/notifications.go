@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"errors"
+	"go-posts/cache"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"go-posts/users"
+	"go-posts/utils"
+	"io"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// maxMentions limits how many users a single post can notify, the rest of the
+// mentions are left as plain text.
+const maxMentions = 10
+
+// NotificationView is a notification decorated with the user who caused it.
+type NotificationView struct {
+	models.Notification
+	Actor *users.Author `json:"actor"`
+}
+
+// notify saves the notifications, leaving out the ones about users' own
+// actions. Failures are only logged, they must not fail the action itself.
+func notify(store storage.Storage, notifications ...models.Notification) {
+	filtered := []models.Notification{}
+	for _, notification := range notifications {
+		if notification.UserID != notification.ActorID {
+			filtered = append(filtered, notification)
+		}
+	}
+
+	if err := store.CreateNotifications(filtered); err != nil {
+		log.Error("Unable to create notifications", "err", err)
+	}
+}
+
+// notifyMentions resolves the users mentioned in a freshly published post
+// through go-users and notifies them in the background.
+func notifyMentions(store storage.Storage, post models.Post) {
+	usernames := utils.ParseMentions(post.Body)
+	if len(usernames) == 0 {
+		return
+	}
+	if len(usernames) > maxMentions {
+		usernames = usernames[:maxMentions]
+	}
+
+	go func() {
+		postID := post.ID
+		notifications := []models.Notification{}
+		for _, username := range usernames {
+			user, err := users.GetUserByUsername(username)
+			if errors.Is(err, users.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				log.Error("Unable to resolve a mentioned user", "username", username, "err", err)
+				continue
+			}
+
+			notifications = append(notifications, models.Notification{
+				UserID:  user.ID,
+				ActorID: post.AuthorID,
+				Type:    models.NotificationMention,
+				PostID:  &postID,
+			})
+		}
+		notify(store, notifications...)
+	}()
+}
+
+// notifyCommented notifies the author of the commented post and, for replies,
+// the author of the parent comment.
+func notifyCommented(store storage.Storage, comment models.Comment) {
+	post, err := store.GetPost(comment.PostID)
+	if err != nil {
+		log.Error("Unable to get the post to notify its author", "post_id", comment.PostID, "err", err)
+		return
+	}
+
+	commentID := comment.ID
+	notifications := []models.Notification{{
+		UserID:    post.AuthorID,
+		ActorID:   comment.AuthorID,
+		Type:      models.NotificationComment,
+		PostID:    &comment.PostID,
+		CommentID: &commentID,
+	}}
+
+	if comment.ParentID != nil {
+		parent, err := store.GetComment(*comment.ParentID)
+		if err != nil {
+			log.Error("Unable to get the parent comment to notify its author", "comment_id", *comment.ParentID, "err", err)
+		} else if parent.AuthorID != post.AuthorID {
+			notifications = append(notifications, models.Notification{
+				UserID:    parent.AuthorID,
+				ActorID:   comment.AuthorID,
+				Type:      models.NotificationComment,
+				PostID:    &comment.PostID,
+				CommentID: &commentID,
+			})
+		}
+	}
+
+	notify(store, notifications...)
+}
+
+type GetNotificationsDto struct {
+	PageID   uint `form:"pageid" binding:"required,min=1"`
+	PageSize uint `form:"pagesize" binding:"required,min=1"`
+}
+
+// GetNotifications returns the caller's notifications, newest first.
+func GetNotifications(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GetNotificationsDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.GetNotifications()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		notifications, err := store.GetNotifications(int(req.PageSize), int((req.PageID-1)*req.PageSize), user.User_Id)
+		if err != nil {
+			storageError(c, err, "Notifications not found")
+			return
+		}
+
+		actorIDs := []uint{}
+		seen := map[uint]bool{}
+		for _, notification := range notifications {
+			if !seen[notification.ActorID] {
+				seen[notification.ActorID] = true
+				actorIDs = append(actorIDs, notification.ActorID)
+			}
+		}
+
+		actors := resolveAuthors(c, cache, actorIDs)
+		views := make([]NotificationView, len(notifications))
+		for i, notification := range notifications {
+			views[i] = NotificationView{Notification: notification, Actor: actors[notification.ActorID]}
+		}
+
+		c.JSON(http.StatusOK, views)
+	}
+}
+
+func CountUnreadNotifications(store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		count, err := store.CountUnreadNotifications(user.User_Id)
+		if err != nil {
+			storageError(c, err, "Notifications not found")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"unread": count})
+	}
+}
+
+// MarkNotificationsReadDto lists the notifications to mark as read. An empty
+// body or an empty list marks all of them.
+type MarkNotificationsReadDto struct {
+	IDs []uint `json:"ids" binding:"max=100"`
+}
+
+func MarkNotificationsRead(store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MarkNotificationsReadDto
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			log.Error("Unable to bind json: handlers.MarkNotificationsRead()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		marked, err := store.MarkNotificationsRead(user.User_Id, req.IDs)
+		if err != nil {
+			storageError(c, err, "Notifications not found")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"marked": marked})
+	}
+}
+
+type NotifyFollowDto struct {
+	FollowerID uint `form:"follower_id" binding:"required,min=1"`
+	FolloweeID uint `form:"followee_id" binding:"required,min=1"`
+}
+
+// NotifyFollow is called by go-users when a user gets a new follower, behind
+// middleware.RequireInternal. The follower's timeline lacks the posts of the
+// followee, so it is dropped and filled again on the next read.
+func NotifyFollow(store storage.Storage, timelines cache.Timelines) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req NotifyFollowDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		following, err := users.IsFollowing(req.FollowerID, req.FolloweeID)
+		if err != nil {
+			log.Error("Unable to check the follow", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"Error": "Unable to check the follow"})
+			return
+		}
+		if !following {
+			c.JSON(http.StatusNotFound, gin.H{"Error": "Follow not found"})
+			return
+		}
+
+		if err := timelines.DropTimeline(c, req.FollowerID); err != nil {
+			log.Error("Unable to drop the timeline", "user_id", req.FollowerID, "err", err)
+		}
+
+		err = store.CreateNotifications([]models.Notification{{
+			UserID:  req.FolloweeID,
+			ActorID: req.FollowerID,
+			Type:    models.NotificationFollow,
+		}})
+		if err != nil {
+			storageError(c, err, "User not found")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"Message": "Success"})
+	}
+}
@@ -1,7 +1,7 @@
 package controllers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"go-posts/cache"
 	"go-posts/server/middleware"
@@ -15,12 +15,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// CreatePostDto creates a published post by default. Status can also be
+// "draft" or "scheduled", scheduled posts are published at PublishAt.
+// AttachmentIDs are up to 4 uploads of the caller not attached to any post
+// yet. QuoteOfID makes the post quote another published post.
 type CreatePostDto struct {
-	Title string `json:"title" binding:"required,min=2,max=50"`
-	Body  string `json:"body" binding:"required,min=2,max=350"`
+	Title         string     `json:"title" binding:"required,min=2,max=50"`
+	Body          string     `json:"body" binding:"required,min=2,max=350"`
+	Status        string     `json:"status"`
+	PublishAt     *time.Time `json:"publish_at"`
+	AttachmentIDs []uint     `json:"attachment_ids" binding:"max=4"`
+	QuoteOfID     *uint      `json:"quote_of_id"`
 }
 
-func CreatePost(store storage.Storage, cache *cache.RedisCache) gin.HandlerFunc {
+func CreatePost(store storage.Storage, cache cache.Cache, timelines cache.Timelines, events cache.Events) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req CreatePostDto
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -29,36 +37,50 @@ func CreatePost(store storage.Storage, cache *cache.RedisCache) gin.HandlerFunc
 			return
 		}
 
+		status, publishAt, err := publication(req.Status, req.PublishAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
 		user, v := middleware.ValidateUser(c)
 
-		if !v {
+		if !v || user == nil {
 			c.JSON(http.StatusUnauthorized, "Not authorized/invalid tokens")
 			return
 		}
+		if !canPost(c, user) {
+			return
+		}
 
 		post := &models.Post{
 			Title:      req.Title,
 			Body:       req.Body,
 			AuthorID:   user.User_Id,
 			LikesCount: 0,
+			Status:     status,
+			PublishAt:  publishAt,
+			QuoteOfID:  req.QuoteOfID,
 		}
 
-		err := store.CreatePost(post)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"Error": err.Error()})
+		if err := store.CreatePost(post, req.AttachmentIDs); err != nil {
+			storageError(c, err, "Quoted post or attachment not found")
 			return
 		}
 
+		if post.Status == models.PostPublished {
+			PublishedPosts(c, store, cache, timelines, events, *post)
+		}
+
 		c.JSON(http.StatusOK, post)
 	}
 }
 
 type GetLatestPostDto struct {
-	PageID   int `form:"pageid" binding:"required,min=1"`
-	PageSize int `form:"pagesize" binding:"required,min=1"`
+	FeedQuery
 }
 
-func GetLatestPosts(store storage.Storage, cache *cache.RedisCache) gin.HandlerFunc {
+func GetLatestPosts(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req GetLatestPostDto
 		if err := c.ShouldBindQuery(&req); err != nil {
@@ -66,50 +88,57 @@ func GetLatestPosts(store storage.Storage, cache *cache.RedisCache) gin.HandlerF
 			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
 			return
 		}
-
-		key := fmt.Sprintf("%d,%d,latest", req.PageSize, req.PageID)
-
-		resJson, err := cache.Client.HGet(c, key, "posts").Result()
-		if err == nil {
-			log.Debug("Result was found in cache, returning it...")
-			var res []models.Post
-			json.Unmarshal([]byte(resJson), &res)
-			c.JSON(http.StatusOK, res)
+		if err := req.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
 			return
 		}
 
-		log.Debug("Result was not found in cache, getting from the database...")
-
-		posts := store.GetLatestPosts(req.PageSize, (req.PageID-1)*req.PageSize)
+		if req.CursorMode() {
+			after, err := decodeCursor(feedLatest, req.Cursor)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+				return
+			}
 
-		go func() {
-			postsJSON, _ := json.Marshal(posts)
+			posts, err := store.GetLatestPostsAfter(req.Limit+1, after)
+			if err != nil {
+				storageError(c, err, "Posts not found")
+				return
+			}
 
-			_, err = cache.Client.HSet(c, key, "posts", postsJSON).Result()
+			posts, next := trimCursorPage(feedLatest, posts, req.Limit)
+			views, err := buildPostViews(c, store, cache, posts, optionalUser(c))
 			if err != nil {
-				log.Error("Unable to set data to cache: ", err)
-			} else {
-				_, err := cache.Client.Expire(c, key, 60*time.Second).Result()
-				if err != nil {
-					log.Error("Unable to set expiration time, running undo changes")
-					_, err := cache.Client.HDel(c, key).Result()
-					if err != nil {
-						log.Error("Unable to delete invalid data, delete manually, key: ", key)
-					}
-				}
+				storageError(c, err, "Posts not found")
+				return
 			}
-		}()
+			c.JSON(http.StatusOK, CursorPage{Posts: views, NextCursor: next})
+			return
+		}
 
-		c.JSON(http.StatusOK, posts)
+		page := fmt.Sprintf("%d,%d", req.PageSize, req.PageID)
+		posts, err := loadFeedPage(c, cache, feedLatest, page, func() ([]models.Post, error) {
+			return store.GetLatestPosts(req.PageSize, req.Offset())
+		})
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+
+		views, err := buildPostViews(c, store, cache, posts, optionalUser(c))
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+		c.JSON(http.StatusOK, views)
 	}
 }
 
 type GetMostLikedPostsDto struct {
-	PageID   uint `form:"pageid" binding:"required,min=1"`
-	PageSize uint `form:"pagesize" binding:"required,min=1"`
+	FeedQuery
 }
 
-func GetMostLikedPosts(store storage.Storage, cache *cache.RedisCache) gin.HandlerFunc {
+func GetMostLikedPosts(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req GetMostLikedPostsDto
 		if err := c.ShouldBindQuery(&req); err != nil {
@@ -117,50 +146,57 @@ func GetMostLikedPosts(store storage.Storage, cache *cache.RedisCache) gin.Handl
 			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
 			return
 		}
-
-		key := fmt.Sprintf("%d,%d,mostliked", req.PageSize, req.PageID)
-
-		resJson, err := cache.Client.HGet(c, key, "posts").Result()
-		if err == nil {
-			log.Debug("Result was found in cache, returning it...")
-			var res []models.Post
-			json.Unmarshal([]byte(resJson), &res)
-			c.JSON(http.StatusOK, res)
+		if err := req.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
 			return
 		}
 
-		log.Debug("Result was not found in cache, getting from the database...")
-
-		posts := store.GetMostLikedPosts(int(req.PageSize), int((req.PageID-1)*req.PageSize))
+		if req.CursorMode() {
+			after, err := decodeCursor(feedMostLiked, req.Cursor)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+				return
+			}
 
-		go func() {
-			postsJSON, _ := json.Marshal(posts)
+			posts, err := store.GetMostLikedPostsAfter(req.Limit+1, after)
+			if err != nil {
+				storageError(c, err, "Posts not found")
+				return
+			}
 
-			_, err = cache.Client.HSet(c, key, "posts", postsJSON).Result()
+			posts, next := trimCursorPage(feedMostLiked, posts, req.Limit)
+			views, err := buildPostViews(c, store, cache, posts, optionalUser(c))
 			if err != nil {
-				log.Error("Unable to set data to cache: ", err)
-			} else {
-				_, err := cache.Client.Expire(c, key, 60*time.Second).Result()
-				if err != nil {
-					log.Error("Unable to set expiration time, running undo changes")
-					_, err := cache.Client.HDel(c, key).Result()
-					if err != nil {
-						log.Error("Unable to delete invalid data, delete manually, key: ", key)
-					}
-				}
+				storageError(c, err, "Posts not found")
+				return
 			}
-		}()
+			c.JSON(http.StatusOK, CursorPage{Posts: views, NextCursor: next})
+			return
+		}
 
-		c.JSON(http.StatusOK, posts)
+		page := fmt.Sprintf("%d,%d", req.PageSize, req.PageID)
+		posts, err := loadFeedPage(c, cache, feedMostLiked, page, func() ([]models.Post, error) {
+			return store.GetMostLikedPosts(req.PageSize, req.Offset())
+		})
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+
+		views, err := buildPostViews(c, store, cache, posts, optionalUser(c))
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+		c.JSON(http.StatusOK, views)
 	}
 }
 
 type GetUsersPostsDto struct {
-	PageID   uint `form:"pageid" binding:"required,min=1"`
-	PageSize uint `form:"pagesize" binding:"required,min=1"`
+	FeedQuery
 }
 
-func GetUsersPosts(store storage.Storage, cache *cache.RedisCache) gin.HandlerFunc {
+func GetUsersPosts(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//validating request
 		var req GetUsersPostsDto
@@ -169,17 +205,53 @@ func GetUsersPosts(store storage.Storage, cache *cache.RedisCache) gin.HandlerFu
 			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
 			return
 		}
+		if err := req.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
 
 		user, isValid := middleware.ValidateUser(c)
-		if !isValid {
+		if !isValid || user == nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
 			return
 		}
 
 		author_id := user.User_Id
 
-		posts := store.GetUsersPosts(int(req.PageSize), int((req.PageID-1)*req.PageSize), author_id)
-		c.JSON(http.StatusOK, posts)
+		if req.CursorMode() {
+			after, err := decodeCursor("user", req.Cursor)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+				return
+			}
+
+			posts, err := store.GetUsersPostsAfter(req.Limit+1, after, author_id)
+			if err != nil {
+				storageError(c, err, "Posts not found")
+				return
+			}
+
+			posts, next := trimCursorPage("user", posts, req.Limit)
+			views, err := buildPostViews(c, store, cache, posts, user)
+			if err != nil {
+				storageError(c, err, "Posts not found")
+				return
+			}
+			c.JSON(http.StatusOK, CursorPage{Posts: views, NextCursor: next})
+			return
+		}
+
+		posts, err := store.GetUsersPosts(req.PageSize, req.Offset(), author_id)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+		views, err := buildPostViews(c, store, cache, posts, user)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+		c.JSON(http.StatusOK, views)
 	}
 }
 
@@ -188,7 +260,7 @@ type GetPostDto struct {
 	PostID uint `form:"post_id" binding:"required"`
 }
 
-func GetPost(store storage.Storage, cache *cache.RedisCache) gin.HandlerFunc {
+func GetPost(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req GetPostDto
 		if err := c.ShouldBindQuery(&req); err != nil {
@@ -197,7 +269,20 @@ func GetPost(store storage.Storage, cache *cache.RedisCache) gin.HandlerFunc {
 			return
 		}
 
-		post := store.GetPost(req.PostID)
+		post, err := store.GetPost(req.PostID)
+		if err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+		if post.Status != models.PostPublished {
+			// unpublished posts are only visible to their author
+			viewer := optionalUser(c)
+			if viewer == nil || viewer.User_Id != post.AuthorID {
+				c.JSON(http.StatusNotFound, gin.H{"Error": "Post not found"})
+				return
+			}
+		}
+
 		c.JSON(http.StatusOK, post)
 	}
 }
@@ -206,8 +291,7 @@ type DeletePostDto struct {
 	PostID uint `form:"post_id" binding:"required"`
 }
 
-// TODO Should only be available for the Author. Currently is available for everyone
-func DeletePost(store storage.Storage, cache *cache.RedisCache) gin.HandlerFunc {
+func DeletePost(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//validating request
 		var req DeletePostDto
@@ -219,28 +303,130 @@ func DeletePost(store storage.Storage, cache *cache.RedisCache) gin.HandlerFunc
 
 		//validating user
 		user, isValid := middleware.ValidateUser(c)
-		if !isValid {
+		if !isValid || user == nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
 			return
 		}
 
 		//checking if the user is actually an author of the post
-		post := store.GetPost(req.PostID)
+		post, err := store.GetPost(req.PostID)
+		if err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
 		if post.AuthorID != user.User_Id {
 			c.JSON(http.StatusForbidden, gin.H{"Error": "Unable to delete other user's posts"})
 			return
 		}
 
-		err := store.DeletePost(req.PostID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err := store.DeletePost(req.PostID); err != nil {
+			storageError(c, err, "Post not found")
 			return
 		}
 
+		bumpFeeds(c, cache, postFeeds...)
+
 		c.JSON(http.StatusOK, gin.H{"Message": "Success"})
 	}
 }
 
+type EditPostDto struct {
+	PostID uint   `json:"post_id" binding:"required"`
+	Title  string `json:"title" binding:"required,min=2,max=50"`
+	Body   string `json:"body" binding:"required,min=2,max=350"`
+}
+
+func EditPost(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		//validating request
+		var req EditPostDto
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error("Unable to bind json: handlers.EditPost()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		//validating user
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		//checking if the user is actually an author of the post
+		post, err := store.GetPost(req.PostID)
+		if err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+		if post.AuthorID != user.User_Id {
+			c.JSON(http.StatusForbidden, gin.H{"Error": "Unable to edit other user's posts"})
+			return
+		}
+		if post.Status != models.PostPublished {
+			c.JSON(http.StatusConflict, gin.H{"Error": "Unpublished posts are edited with /posts/drafts/edit"})
+			return
+		}
+		if post.RepostOfID != nil {
+			c.JSON(http.StatusConflict, gin.H{"Error": "Reposts can not be edited"})
+			return
+		}
+
+		post, err = store.UpdatePost(req.PostID, req.Title, req.Body)
+		if err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+
+		updatePostTags(store, post.ID, post.Body)
+
+		bumpFeeds(c, cache, postFeeds...)
+
+		c.JSON(http.StatusOK, post)
+	}
+}
+
+type GetPostRevisionsDto struct {
+	PostID uint `form:"post_id" binding:"required"`
+}
+
+func GetPostRevisions(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GetPostRevisionsDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.GetPostRevisions()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		// revisions are as visible as the post itself: trashed, draft and
+		// scheduled posts only to their author
+		post, err := store.GetPost(req.PostID)
+		if errors.Is(err, storage.ErrNotFound) {
+			post, err = store.GetDeletedPost(req.PostID)
+		}
+		if err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+		if post.Status != models.PostPublished || post.DeletedAt.Valid {
+			viewer := optionalUser(c)
+			if viewer == nil || viewer.User_Id != post.AuthorID {
+				c.JSON(http.StatusNotFound, gin.H{"Error": "Post not found"})
+				return
+			}
+		}
+
+		revisions, err := store.GetPostRevisions(req.PostID)
+		if err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+
+		c.JSON(http.StatusOK, revisions)
+	}
+}
+
 type CountPostsDto struct {
 	User_id string `form:"id" binding:"required,min=1"`
 }
@@ -258,7 +444,11 @@ func CountPosts(storage storage.Storage) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{})
 			return
 		}
-		amount := storage.CountPosts(uint(id))
+		amount, err := storage.CountPosts(uint(id))
+		if err != nil {
+			storageError(c, err, "User not found")
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{"amount": amount})
 	}
 }
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"go-posts/cache"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+type RepostDto struct {
+	PostID uint `form:"post_id" binding:"required"`
+}
+
+// Repost shares a published post in the caller's name. Reposting a repost
+// shares its original.
+func Repost(store storage.Storage, cache cache.Cache, timelines cache.Timelines, events cache.Events) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RepostDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.Repost()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+		if !canPost(c, user) {
+			return
+		}
+
+		post := &models.Post{
+			AuthorID:   user.User_Id,
+			RepostOfID: &req.PostID,
+			Status:     models.PostPublished,
+		}
+
+		if err := store.CreatePost(post, nil); err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+
+		PublishedPosts(c, store, cache, timelines, events, *post)
+
+		c.JSON(http.StatusOK, post)
+	}
+}
+
+type UnrepostDto struct {
+	PostID uint `form:"post_id" binding:"required"`
+}
+
+func Unrepost(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UnrepostDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.Unrepost()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		if err := store.DeleteRepost(user.User_Id, req.PostID); err != nil {
+			storageError(c, err, "Post not found")
+			return
+		}
+
+		bumpFeeds(c, cache, postFeeds...)
+
+		c.JSON(http.StatusOK, gin.H{"Message": "Success"})
+	}
+}
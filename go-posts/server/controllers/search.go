@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-posts/cache"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// A query is considered popular, and its results get cached, once it was
+	// asked searchPopularHits times within searchPopularWindow.
+	searchPopularHits   = 3
+	searchPopularWindow = 10 * time.Minute
+)
+
+type SearchPostsDto struct {
+	Query    string    `form:"q" binding:"required,min=2,max=100"`
+	AuthorID uint      `form:"author_id"`
+	From     time.Time `form:"from" time_format:"2006-01-02"`
+	To       time.Time `form:"to" time_format:"2006-01-02"`
+	Cursor   string    `form:"cursor"`
+	Limit    int       `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// SearchResultView is a search result decorated like the feed posts.
+type SearchResultView struct {
+	PostView
+	Rank         float32 `json:"rank"`
+	TitleSnippet string  `json:"title_snippet"`
+	BodySnippet  string  `json:"body_snippet"`
+}
+
+type SearchPage struct {
+	Results    []SearchResultView `json:"results"`
+	NextCursor string             `json:"next_cursor"`
+}
+
+// searchCacheEntry is what gets cached for a popular query. Viewer specific
+// flags are not part of it.
+type searchCacheEntry struct {
+	Results    []storage.SearchResult
+	NextCursor string
+}
+
+// searchQueryID identifies a search request, including its page.
+func searchQueryID(req *SearchPostsDto) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%q|%d|%s|%s|%s|%d",
+		req.Query, req.AuthorID, req.From.Format(time.DateOnly), req.To.Format(time.DateOnly), req.Cursor, req.Limit)))
+	return hex.EncodeToString(sum[:])
+}
+
+// SearchPosts runs a full-text search over post titles and bodies. Dates are
+// inclusive days, pagination is cursor based only.
+func SearchPosts(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SearchPostsDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.SearchPosts()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+		if req.Limit == 0 {
+			req.Limit = 20
+		}
+
+		after, err := decodeCursor(feedSearch, req.Cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		queryID := searchQueryID(&req)
+
+		// Cached results live in the search feed generation, so they are
+		// dropped whenever posts change.
+		key, err := cache.TagKey(c, feedSearch, queryID)
+		if err != nil {
+			log.Error("Unable to get search cache key", "err", err)
+		}
+
+		var entry searchCacheEntry
+		if key != "" {
+			resJson, err := cache.Get(c, key)
+			if err == nil && json.Unmarshal(resJson, &entry) == nil {
+				log.Debug("Search result was found in cache, returning it...")
+				page, err := buildSearchPage(c, store, cache, entry, optionalUser(c))
+				if err != nil {
+					storageError(c, err, "Posts not found")
+					return
+				}
+				c.JSON(http.StatusOK, page)
+				return
+			}
+		}
+
+		query := storage.SearchQuery{
+			Text:     req.Query,
+			AuthorID: req.AuthorID,
+			Limit:    req.Limit + 1,
+			After:    after,
+		}
+		if !req.From.IsZero() {
+			query.From = &req.From
+		}
+		if !req.To.IsZero() {
+			to := req.To.AddDate(0, 0, 1)
+			query.To = &to
+		}
+
+		results, err := store.SearchPosts(query)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+
+		entry = searchCacheEntry{Results: results}
+		if len(results) > req.Limit {
+			entry.Results = results[:req.Limit]
+			last := entry.Results[req.Limit-1]
+			entry.NextCursor = encodeCursor(feedSearch, storage.PostCursor{Rank: last.Rank, ID: last.ID})
+		}
+
+		hits, err := cache.Increment(c, "hits:search:"+queryID, searchPopularWindow)
+		if err != nil {
+			log.Error("Unable to count search hits", "err", err)
+		} else if hits >= searchPopularHits && key != "" {
+			entryJSON, _ := json.Marshal(entry)
+			if err := cache.Set(c, key, entryJSON, searchTTL); err != nil {
+				log.Error("Unable to set data to cache: ", err)
+			}
+		}
+
+		page, err := buildSearchPage(c, store, cache, entry, optionalUser(c))
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+func buildSearchPage(ctx context.Context, store storage.Storage, cache cache.Cache, entry searchCacheEntry, viewer *middleware.UserInfo) (SearchPage, error) {
+	posts := make([]models.Post, len(entry.Results))
+	for i, result := range entry.Results {
+		posts[i] = result.Post
+	}
+
+	views, err := buildPostViews(ctx, store, cache, posts, viewer)
+	if err != nil {
+		return SearchPage{}, err
+	}
+	page := SearchPage{Results: make([]SearchResultView, len(views)), NextCursor: entry.NextCursor}
+	for i, view := range views {
+		page.Results[i] = SearchResultView{
+			PostView:     view,
+			Rank:         entry.Results[i].Rank,
+			TitleSnippet: entry.Results[i].TitleSnippet,
+			BodySnippet:  entry.Results[i].BodySnippet,
+		}
+	}
+	return page, nil
+}
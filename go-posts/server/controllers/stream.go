@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-posts/cache"
+	"go-posts/storage/models"
+	"go-posts/utils"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// streamChannel is the Events channel the feed updates are published to.
+const streamChannel = "posts:stream"
+
+// Kinds of stream events.
+const (
+	streamPost  = "post"
+	streamLikes = "likes"
+)
+
+// streamBuffer is how many events a connected client can lag behind before
+// it starts missing them.
+const streamBuffer = 16
+
+// How often an idle stream gets a comment line, which keeps proxies from
+// closing it, and how many streams a replica serves at once.
+var (
+	StreamHeartbeat  = utils.GetEnvDuration("STREAM_HEARTBEAT", 15*time.Second)
+	StreamMaxClients = utils.GetEnvInt("STREAM_MAX_CLIENTS", 1000)
+)
+
+// StreamEvent is a feed update as it travels between the replicas. ID is the
+// id of the post the event is about.
+type StreamEvent struct {
+	Type string          `json:"type"`
+	ID   uint            `json:"id"`
+	Data json.RawMessage `json:"data"`
+}
+
+// LikesUpdate is the data of a likes event.
+type LikesUpdate struct {
+	PostID     uint `json:"post_id"`
+	LikesCount uint `json:"likes_count"`
+}
+
+// StreamHub relays the feed updates of every replica to the streams
+// connected to this one, so a replica holds a single subscription however
+// many clients it serves.
+type StreamHub struct {
+	events  cache.Events
+	mu      sync.Mutex
+	clients map[chan StreamEvent]bool
+}
+
+func NewStreamHub(events cache.Events) *StreamHub {
+	return &StreamHub{events: events, clients: make(map[chan StreamEvent]bool)}
+}
+
+// Run relays the published events to the connected clients until the
+// subscription ends.
+func (hub *StreamHub) Run(ctx context.Context) error {
+	messages, err := hub.events.Subscribe(ctx, streamChannel)
+	if err != nil {
+		return err
+	}
+
+	for message := range messages {
+		var event StreamEvent
+		if err := json.Unmarshal(message, &event); err != nil {
+			log.Error("Unable to decode stream event", "err", err)
+			continue
+		}
+		hub.broadcast(event)
+	}
+	return nil
+}
+
+// broadcast hands the event to every client. Clients which are too slow to
+// take it miss it rather than hold the others back.
+func (hub *StreamHub) broadcast(event StreamEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for client := range hub.clients {
+		select {
+		case client <- event:
+		default:
+		}
+	}
+}
+
+// subscribe registers a new client, it fails once the replica serves
+// StreamMaxClients of them.
+func (hub *StreamHub) subscribe() (chan StreamEvent, bool) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if len(hub.clients) >= StreamMaxClients {
+		return nil, false
+	}
+	client := make(chan StreamEvent, streamBuffer)
+	hub.clients[client] = true
+	return client, true
+}
+
+func (hub *StreamHub) unsubscribe(client chan StreamEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	delete(hub.clients, client)
+}
+
+// publishStreamEvent sends a feed update to the streams of every replica.
+// Failures are only logged, clients can always fall back to polling.
+func publishStreamEvent(ctx context.Context, events cache.Events, kind string, id uint, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Error("Unable to encode stream event", "err", err)
+		return
+	}
+
+	message, _ := json.Marshal(StreamEvent{Type: kind, ID: id, Data: payload})
+	if err := events.Publish(ctx, streamChannel, message); err != nil {
+		log.Error("Unable to publish stream event", "type", kind, "err", err)
+	}
+}
+
+// publishLikes streams the new likes counter of the post.
+func publishLikes(ctx context.Context, events cache.Events, post models.Post) {
+	publishStreamEvent(ctx, events, streamLikes, post.ID, LikesUpdate{PostID: post.ID, LikesCount: post.LikesCount})
+}
+
+type StreamPostsDto struct {
+	Likes bool `form:"likes"`
+}
+
+// StreamPosts streams the newly published posts as Server-Sent Events, which
+// saves clients from polling /posts/latest. With likes=true the changes of
+// likes counters are streamed too.
+func StreamPosts(hub *StreamHub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req StreamPostsDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.StreamPosts()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		client, ok := hub.subscribe()
+		if !ok {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"Error": "Too many streams, try again later"})
+			return
+		}
+		defer hub.unsubscribe(client)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		// tells nginx-like proxies not to buffer the stream
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(StreamHeartbeat)
+		defer heartbeat.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			case event := <-client:
+				if event.Type == streamLikes && !req.Likes {
+					return true
+				}
+				fmt.Fprintf(w, "event: %s\nid: %d\ndata: %s\n\n", event.Type, event.ID, event.Data)
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-posts/cache"
+	"go-posts/storage"
+	"go-posts/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// updatePostTags attaches the hashtags found in the body to the post. Tags are
+// secondary data, so a failure is logged instead of failing the request.
+func updatePostTags(store storage.Storage, postID uint, body string) {
+	if err := store.SetPostTags(postID, utils.ParseHashtags(body)); err != nil {
+		log.Error("Unable to set post tags", "post_id", postID, "err", err)
+	}
+}
+
+type GetTagPostsDto struct {
+	PageID   uint `form:"pageid" binding:"required,min=1"`
+	PageSize uint `form:"pagesize" binding:"required,min=1"`
+}
+
+func GetTagPosts(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GetTagPostsDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.GetTagPosts()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		tag := strings.ToLower(strings.TrimPrefix(c.Param("tag"), "#"))
+		if tag == "" || len([]rune(tag)) > utils.MaxHashtagLength {
+			c.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid tag"})
+			return
+		}
+
+		posts, err := store.GetPostsByTag(int(req.PageSize), int((req.PageID-1)*req.PageSize), tag)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+
+		views, err := buildPostViews(c, store, cache, posts, optionalUser(c))
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+		c.JSON(http.StatusOK, views)
+	}
+}
+
+type GetTrendingTagsDto struct {
+	Hours int `form:"hours" binding:"omitempty,min=1,max=168"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+// GetTrendingTags returns the most used tags over the last hours (24 by
+// default). The result is cached like a feed page.
+func GetTrendingTags(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GetTrendingTagsDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.GetTrendingTags()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+		if req.Hours == 0 {
+			req.Hours = 24
+		}
+		if req.Limit == 0 {
+			req.Limit = 10
+		}
+
+		page := fmt.Sprintf("%d,%d", req.Hours, req.Limit)
+		data, err := loadFeed(c, cache, feedTrending, page, func() ([]byte, error) {
+			since := time.Now().Add(-time.Duration(req.Hours) * time.Hour)
+			tags, err := store.GetTrendingTags(req.Limit, since)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(tags)
+		})
+		if err != nil {
+			storageError(c, err, "Tags not found")
+			return
+		}
+
+		var tags []storage.TagCount
+		if err := json.Unmarshal(data, &tags); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"Error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, tags)
+	}
+}
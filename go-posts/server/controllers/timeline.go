@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"context"
+	"go-posts/cache"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"go-posts/users"
+	"go-posts/utils"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// Authors with at most timelineFanOutLimit followers get their posts pushed
+// into their followers' timelines when they are created (fan-out-on-write).
+// Posts of bigger authors are merged in when a timeline is read
+// (fan-out-on-read), so a single post never costs millions of writes.
+var timelineFanOutLimit = uint(utils.GetEnvInt("TIMELINE_FANOUT_LIMIT", 1000))
+
+// fanOutPost pushes a new post into the timelines of the author's followers,
+// unless the author is too big for fan-out-on-write. The follower count is
+// checked first, so big authors never download their follower list.
+func fanOutPost(timelines cache.Timelines, post models.Post) {
+	go func() {
+		count, err := users.GetFollowersCount(post.AuthorID)
+		if err != nil {
+			log.Error("Unable to count followers for the timeline fan-out", "author_id", post.AuthorID, "err", err)
+			return
+		}
+		if count == 0 || count > timelineFanOutLimit {
+			return
+		}
+
+		followers, err := users.GetFollowerIDs(post.AuthorID)
+		if err != nil {
+			log.Error("Unable to get followers for the timeline fan-out", "author_id", post.AuthorID, "err", err)
+			return
+		}
+
+		if err := timelines.PushToTimelines(context.Background(), followers, post.ID, post.CreatedAt); err != nil {
+			log.Error("Unable to push post to timelines", "post_id", post.ID, "err", err)
+		}
+	}()
+}
+
+// fillTimeline builds the user's timeline from the newest posts of the given
+// small authors and reports whether it succeeded.
+func fillTimeline(ctx context.Context, store storage.Storage, timelines cache.Timelines, userID uint, authorIDs []uint) bool {
+	posts, err := store.GetPostsByAuthorsAfter(cache.TimelineLength, nil, authorIDs)
+	if err != nil {
+		log.Error("Unable to read posts for the timeline", "user_id", userID, "err", err)
+		return false
+	}
+
+	entries := make([]cache.TimelineEntry, len(posts))
+	for i, post := range posts {
+		entries[i] = cache.TimelineEntry{PostID: post.ID, CreatedAt: post.CreatedAt}
+	}
+	if err := timelines.FillTimeline(ctx, userID, entries); err != nil {
+		log.Error("Unable to fill the timeline", "user_id", userID, "err", err)
+		return false
+	}
+	return true
+}
+
+type GetTimelineDto struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// GetTimeline returns the posts of the authors followed by the caller, newest
+// first, paginated with cursors.
+func GetTimeline(store storage.Storage, cache cache.Cache, timelines cache.Timelines) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GetTimelineDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.GetTimeline()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+		if req.Limit == 0 {
+			req.Limit = 20
+		}
+
+		after, err := decodeCursor("timeline", req.Cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		followed, err := users.GetFollowedUsers(user.User_Id)
+		if err != nil {
+			log.Error("Unable to get followed users", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"Error": "Unable to get followed users"})
+			return
+		}
+
+		posts, err := readTimeline(c, store, timelines, user.User_Id, followed, after, req.Limit+1)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+
+		posts, next := trimCursorPage("timeline", posts, req.Limit)
+		views, err := buildPostViews(c, store, cache, posts, user)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+		c.JSON(http.StatusOK, CursorPage{Posts: views, NextCursor: next})
+	}
+}
+
+// readTimeline merges the materialised timeline of small authors with the
+// posts of big authors read from the database and returns up to limit posts
+// after the cursor. A missing timeline is filled first. Once the timeline runs
+// out, the posts of small authors are read from the database too, since it
+// only keeps the newest ones.
+func readTimeline(ctx context.Context, store storage.Storage, timelines cache.Timelines, userID uint, followed []users.FollowedUser, after *storage.PostCursor, limit int) ([]models.Post, error) {
+	small := map[uint]bool{}
+	smallIDs := []uint{}
+	large := []uint{}
+	for _, author := range followed {
+		if author.FollowersCount <= timelineFanOutLimit {
+			small[author.ID] = true
+			smallIDs = append(smallIDs, author.ID)
+		} else {
+			large = append(large, author.ID)
+		}
+	}
+
+	// Without a usable timeline everything is read from the database.
+	if len(small) > 0 {
+		hasTimeline, err := timelines.HasTimeline(ctx, userID)
+		if err != nil {
+			log.Error("Unable to check the timeline cache", "err", err)
+		} else if !hasTimeline {
+			hasTimeline = fillTimeline(ctx, store, timelines, userID, smallIDs)
+		}
+		if !hasTimeline {
+			large = append(large, smallIDs...)
+			small = map[uint]bool{}
+		}
+	}
+
+	posts := []models.Post{}
+	seen := map[uint]bool{}
+	add := func(post models.Post) bool {
+		if seen[post.ID] || !isAfterCursor(post, after) {
+			return false
+		}
+		seen[post.ID] = true
+		posts = append(posts, post)
+		return true
+	}
+
+	if len(small) > 0 {
+		var before time.Time
+		if after != nil {
+			before = after.CreatedAt
+		}
+
+		// Entries of unfollowed authors and of deleted posts are skipped, so
+		// keep reading until the page is filled or the timeline runs out.
+		found, offset, exhausted := 0, 0, false
+		for found < limit && !exhausted {
+			batch := 2 * limit
+			ids, err := timelines.GetTimeline(ctx, userID, before, offset, batch)
+			if err != nil {
+				return nil, err
+			}
+			offset += batch
+			exhausted = len(ids) < batch
+
+			cached, err := store.GetPostsByIDs(ids)
+			if err != nil {
+				return nil, err
+			}
+			for _, post := range cached {
+				if small[post.AuthorID] && add(post) {
+					found++
+				}
+			}
+		}
+
+		if exhausted && found < limit {
+			large = append(large, smallIDs...)
+		}
+	}
+
+	if len(large) > 0 {
+		read, err := store.GetPostsByAuthorsAfter(limit, after, large)
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range read {
+			add(post)
+		}
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+// isAfterCursor reports whether the post comes after the cursor in the
+// (created_at, id) descending order.
+func isAfterCursor(post models.Post, after *storage.PostCursor) bool {
+	if after == nil {
+		return true
+	}
+	if !post.CreatedAt.Equal(after.CreatedAt) {
+		return post.CreatedAt.Before(after.CreatedAt)
+	}
+	return post.ID < after.ID
+}
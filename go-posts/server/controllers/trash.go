@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"go-posts/cache"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"go-posts/utils"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// TrashRetention is how long a deleted post can be restored before the
+// purger deletes it for good.
+var TrashRetention = utils.GetEnvDuration("TRASH_RETENTION", 30*24*time.Hour)
+
+// TrashedPost is a deleted post together with the moment it will be purged.
+type TrashedPost struct {
+	models.Post
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+type GetTrashDto struct {
+	PageID   uint `form:"pageid" binding:"required,min=1"`
+	PageSize uint `form:"pagesize" binding:"required,min=1"`
+}
+
+// GetTrash returns the caller's deleted posts, most recently deleted first.
+func GetTrash(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GetTrashDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.GetTrash()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		posts, err := store.GetDeletedPosts(int(req.PageSize), int((req.PageID-1)*req.PageSize), user.User_Id)
+		if err != nil {
+			storageError(c, err, "Posts not found")
+			return
+		}
+
+		trashed := make([]TrashedPost, len(posts))
+		for i, post := range posts {
+			trashed[i] = TrashedPost{Post: post, PurgeAt: post.DeletedAt.Time.Add(TrashRetention)}
+		}
+
+		c.JSON(http.StatusOK, trashed)
+	}
+}
+
+type RestorePostDto struct {
+	PostID uint `form:"post_id" binding:"required"`
+}
+
+// RestorePost undeletes one of the caller's posts, as long as it was deleted
+// less than TrashRetention ago.
+func RestorePost(store storage.Storage, cache cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RestorePostDto
+		if err := c.ShouldBindQuery(&req); err != nil {
+			log.Error("Unable to bind query: handlers.RestorePost()", "err", err)
+			c.JSON(http.StatusBadRequest, gin.H{"Error": err.Error()})
+			return
+		}
+
+		user, isValid := middleware.ValidateUser(c)
+		if !isValid || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"Error": "Not authorized / invalid tokens"})
+			return
+		}
+
+		post, err := store.GetDeletedPost(req.PostID)
+		if err != nil {
+			storageError(c, err, "Post not found in trash")
+			return
+		}
+		if post.AuthorID != user.User_Id {
+			c.JSON(http.StatusForbidden, gin.H{"Error": "Unable to restore other user's posts"})
+			return
+		}
+		if time.Since(post.DeletedAt.Time) > TrashRetention {
+			c.JSON(http.StatusGone, gin.H{"Error": "Post can no longer be restored"})
+			return
+		}
+
+		post, err = store.RestorePost(req.PostID)
+		if err != nil {
+			storageError(c, err, "Post not found in trash")
+			return
+		}
+
+		bumpFeeds(c, cache, postFeeds...)
+
+		c.JSON(http.StatusOK, post)
+	}
+}
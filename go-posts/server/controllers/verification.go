@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"go-posts/server/middleware"
+	"go-posts/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail keeps users from posting until they verify their
+// e-mail address with go-users, set by the REQUIRE_VERIFIED_EMAIL ENV.
+var RequireVerifiedEmail = utils.GetEnvBool("REQUIRE_VERIFIED_EMAIL", false)
+
+// canPost reports whether the user may publish content. Otherwise it writes
+// 403 and returns false.
+func canPost(c *gin.Context, user *middleware.UserInfo) bool {
+	if RequireVerifiedEmail && !user.EmailVerified {
+		c.JSON(http.StatusForbidden, gin.H{"Error": "Verify your e-mail address before posting"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"go-posts/cache"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"go-posts/users"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PostView is a post as it is returned by the feed endpoints, decorated with
+// its author, its attachments and the data that depends on who is asking.
+// Reposts and quotes embed their original, or report it as unavailable once
+// it is deleted.
+type PostView struct {
+	models.Post
+	Author              *users.Author    `json:"author"`
+	Attachments         []AttachmentView `json:"attachments"`
+	Liked               bool             `json:"liked"`
+	Reposted            bool             `json:"reposted"`
+	Bookmarked          bool             `json:"bookmarked"`
+	Original            *PostView        `json:"original,omitempty"`
+	OriginalUnavailable bool             `json:"original_unavailable,omitempty"`
+}
+
+// optionalUser returns the caller if the request carries valid tokens and nil
+// otherwise. It is meant for the free endpoints where authentication only
+// enriches the response.
+func optionalUser(c *gin.Context) *middleware.UserInfo {
+	if _, err := c.Cookie("access_token"); err != nil {
+		return nil
+	}
+
+	user, isValid := middleware.ValidateUser(c)
+	if !isValid {
+		return nil
+	}
+	return user
+}
+
+// buildPostViews decorates posts with their authors and the viewer specific
+// flags, and embeds the originals of reposts and quotes. A nil viewer gets all
+// flags unset. Reposts and quotes whose original was deleted or unpublished
+// are marked with OriginalUnavailable.
+func buildPostViews(ctx context.Context, store storage.Storage, cache cache.Cache, posts []models.Post, viewer *middleware.UserInfo) ([]PostView, error) {
+	views, err := decoratePosts(ctx, store, cache, posts, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	originalIDs := []uint{}
+	for _, post := range posts {
+		if originalID := post.OriginalID(); originalID != nil {
+			originalIDs = append(originalIDs, *originalID)
+		}
+	}
+	if len(originalIDs) == 0 {
+		return views, nil
+	}
+
+	originals, err := store.GetPostsByIDs(originalIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// originals are embedded one level deep, so a quoted quote only carries
+	// the id of its own original
+	decorated, err := decoratePosts(ctx, store, cache, originals, viewer)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[uint]*PostView{}
+	for _, view := range decorated {
+		view := view
+		byID[view.ID] = &view
+	}
+	for i := range views {
+		originalID := views[i].OriginalID()
+		if originalID == nil {
+			continue
+		}
+		if original, ok := byID[*originalID]; ok {
+			views[i].Original = original
+		} else {
+			views[i].OriginalUnavailable = true
+		}
+	}
+	return views, nil
+}
+
+// decoratePosts builds the views of posts without embedding their originals.
+// Authors which can not be resolved are left out, but storage failures are
+// returned rather than serving posts with wrong flags.
+func decoratePosts(ctx context.Context, store storage.Storage, cache cache.Cache, posts []models.Post, viewer *middleware.UserInfo) ([]PostView, error) {
+	views := make([]PostView, len(posts))
+	authorIDs := []uint{}
+	seen := map[uint]bool{}
+	for i, post := range posts {
+		views[i] = PostView{Post: post}
+		if !seen[post.AuthorID] {
+			seen[post.AuthorID] = true
+			authorIDs = append(authorIDs, post.AuthorID)
+		}
+	}
+
+	authors := resolveAuthors(ctx, cache, authorIDs)
+	for i := range views {
+		views[i].Author = authors[views[i].AuthorID]
+	}
+
+	if len(posts) == 0 {
+		return views, nil
+	}
+
+	ids := make([]uint, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	attachments, err := store.GetAttachments(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range views {
+		views[i].Attachments = []AttachmentView{}
+		for _, attachment := range attachments[views[i].ID] {
+			views[i].Attachments = append(views[i].Attachments, newAttachmentView(attachment))
+		}
+	}
+
+	if viewer == nil {
+		return views, nil
+	}
+
+	liked, err := store.GetLikedPostIDs(viewer.User_Id, ids)
+	if err != nil {
+		return nil, err
+	}
+	reposted, err := store.GetRepostedPostIDs(viewer.User_Id, ids)
+	if err != nil {
+		return nil, err
+	}
+	bookmarked, err := store.GetBookmarkedPostIDs(viewer.User_Id, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range views {
+		views[i].Liked = liked[views[i].ID]
+		views[i].Reposted = reposted[views[i].ID]
+		views[i].Bookmarked = bookmarked[views[i].ID]
+	}
+	return views, nil
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-posts/cache"
+	"go-posts/server/middleware"
+	"go-posts/storage"
+	"go-posts/storage/models"
+	"testing"
+)
+
+// originalsStore fails GetPostsByIDs with err and GetLikedPostIDs with
+// likedErr when they are set.
+type originalsStore struct {
+	*storage.MemoryStore
+	err      error
+	likedErr error
+}
+
+func (store *originalsStore) GetLikedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error) {
+	if store.likedErr != nil {
+		return nil, store.likedErr
+	}
+	return store.MemoryStore.GetLikedPostIDs(userID, postIDs)
+}
+
+func (store *originalsStore) GetPostsByIDs(postIDs []uint) ([]models.Post, error) {
+	if store.err != nil {
+		return nil, store.err
+	}
+	return store.MemoryStore.GetPostsByIDs(postIDs)
+}
+
+func TestBuildPostViewsOriginals(t *testing.T) {
+	ctx := context.Background()
+	memory := storage.NewMemoryStore()
+	backend := cache.NewMemoryCache(10)
+	// authors come from the cache, so the users service is never called
+	backend.Set(ctx, authorKey(1), []byte(`{"id":1}`), 0)
+
+	create := func(post models.Post) models.Post {
+		post.AuthorID = 1
+		if err := memory.CreatePost(&post, nil); err != nil {
+			t.Fatalf("CreatePost() = %v", err)
+		}
+		return post
+	}
+	live := create(models.Post{Title: "live", Body: "body"})
+	gone := create(models.Post{Title: "gone", Body: "body"})
+	quoteOfLive := create(models.Post{Title: "quote", Body: "body", QuoteOfID: &live.ID})
+	quoteOfGone := create(models.Post{Title: "quote", Body: "body", QuoteOfID: &gone.ID})
+	memory.DeletePost(gone.ID)
+	posts := []models.Post{quoteOfLive, quoteOfGone}
+
+	views, err := buildPostViews(ctx, &originalsStore{MemoryStore: memory}, backend, posts, nil)
+	if err != nil {
+		t.Fatalf("buildPostViews() = %v", err)
+	}
+	if views[0].Original == nil || views[0].Original.ID != live.ID || views[0].OriginalUnavailable {
+		t.Errorf("quote of a live post: original %v, unavailable %v", views[0].Original, views[0].OriginalUnavailable)
+	}
+	if views[1].Original != nil || !views[1].OriginalUnavailable {
+		t.Errorf("quote of a deleted post: original %v, unavailable %v", views[1].Original, views[1].OriginalUnavailable)
+	}
+
+	failure := errors.New("database is down")
+	if _, err := buildPostViews(ctx, &originalsStore{MemoryStore: memory, err: failure}, backend, posts, nil); !errors.Is(err, failure) {
+		t.Errorf("buildPostViews() with a failing storage = %v, want %v", err, failure)
+	}
+
+	viewer := &middleware.UserInfo{User_Id: 2}
+	if _, err := buildPostViews(ctx, &originalsStore{MemoryStore: memory, likedErr: failure}, backend, posts, viewer); !errors.Is(err, failure) {
+		t.Errorf("buildPostViews() with failing viewer flags = %v, want %v", err, failure)
+	}
+}
@@ -13,15 +13,17 @@ import (
 )
 
 type tokens struct {
-	User_Id       uint   `json:"user_id"`
-	Username      string `json:"username"`
-	Access_Token  string `json:"access_token"`
-	Refresh_Token string `json:"refresh_token"`
+	User_Id        uint   `json:"user_id"`
+	Username       string `json:"username"`
+	Email_Verified bool   `json:"email_verified"`
+	Access_Token   string `json:"access_token"`
+	Refresh_Token  string `json:"refresh_token"`
 }
 
 type UserInfo struct {
-	User_Id  uint
-	Username string
+	User_Id       uint
+	Username      string
+	EmailVerified bool
 }
 
 // TODO Needs refactoring
@@ -50,7 +52,18 @@ func ValidateUser(c *gin.Context) (*UserInfo, bool) {
 		return nil, false
 	}
 
-	resp, err := http.Post(targetUrl, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest(http.MethodPost, targetUrl, bytes.NewBuffer(jsonData))
+	if err != nil {
+		//Debug only
+		fmt.Println("Unable to create the request")
+		return nil, false
+	}
+	// go-users records the device of the session, not go-posts
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", c.ClientIP())
+	req.Header.Set("User-Agent", c.Request.UserAgent())
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		//Debug only
 		fmt.Println("Unable to retrieve response from Users loadbalancer")
@@ -87,5 +100,5 @@ func ValidateUser(c *gin.Context) (*UserInfo, bool) {
 
 	fmt.Println("-------------------------" + strconv.FormatUint(uint64(tokens.User_Id), 10) + " " + tokens.Username)
 
-	return &UserInfo{User_Id: tokens.User_Id, Username: tokens.Username}, true
+	return &UserInfo{User_Id: tokens.User_Id, Username: tokens.Username, EmailVerified: tokens.Email_Verified}, true
 }
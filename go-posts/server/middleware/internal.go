@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InternalTokenHeader carries the secret shared by the services, which the
+// internal callbacks of go-users are authenticated with.
+const InternalTokenHeader = "X-Internal-Token"
+
+// RequireInternal lets only the requests carrying the INTERNAL_TOKEN ENV in
+// the InternalTokenHeader through. Without INTERNAL_TOKEN every request is
+// rejected, as the routes behind it are reachable through the loadbalancer.
+func RequireInternal() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := os.Getenv("INTERNAL_TOKEN")
+		token := c.GetHeader(InternalTokenHeader)
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"Error": "Internal route"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireInternal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/internal", RequireInternal(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		secret string
+		token  string
+		want   int
+	}{
+		{name: "matching token", secret: "secret", token: "secret", want: http.StatusOK},
+		{name: "wrong token", secret: "secret", token: "guess", want: http.StatusForbidden},
+		{name: "missing token", secret: "secret", want: http.StatusForbidden},
+		{name: "no secret configured", token: "", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Setenv("INTERNAL_TOKEN", tt.secret)
+		req := httptest.NewRequest(http.MethodPost, "/internal", nil)
+		if tt.token != "" {
+			req.Header.Set(InternalTokenHeader, tt.token)
+		}
+		res := httptest.NewRecorder()
+		engine.ServeHTTP(res, req)
+		if res.Code != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.name, res.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"go-posts/server/controllers"
+	"go-posts/utils"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// How often the purger looks for expired posts and how many posts it deletes
+// per transaction. Uploads not attached to a post within attachmentOrphanTTL
+// are purged too.
+var (
+	trashPurgeInterval  = utils.GetEnvDuration("TRASH_PURGE_INTERVAL", time.Hour)
+	trashPurgeBatch     = utils.GetEnvInt("TRASH_PURGE_BATCH", 500)
+	attachmentOrphanTTL = utils.GetEnvDuration("ATTACHMENT_ORPHAN_TTL", 24*time.Hour)
+)
+
+// RunTrashPurger permanently deletes, every trashPurgeInterval, the posts
+// which have been deleted for longer than controllers.TrashRetention. It
+// never returns, so it is meant to be started in its own goroutine.
+func (s *Server) RunTrashPurger() {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		s.purgeTrash()
+		s.purgeAttachments()
+		<-ticker.C
+	}
+}
+
+func (s *Server) purgeTrash() {
+	deletedBefore := time.Now().Add(-controllers.TrashRetention)
+
+	var total int64
+	for {
+		purged, err := s.Store.PurgeDeletedPosts(deletedBefore, trashPurgeBatch)
+		if err != nil {
+			log.Error("Unable to purge deleted posts", "err", err)
+			return
+		}
+
+		total += purged
+		if purged == 0 || purged < int64(trashPurgeBatch) {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info("Purged deleted posts", "count", total)
+	}
+}
+
+// purgeAttachments deletes the attachments nobody can reach anymore. Blobs go
+// first, so a failure never leaves a blob behind without a row pointing at it.
+func (s *Server) purgeAttachments() {
+	ctx := context.Background()
+	uploadedBefore := time.Now().Add(-attachmentOrphanTTL)
+
+	var total int
+	for {
+		attachments, err := s.Store.GetOrphanedAttachments(uploadedBefore, trashPurgeBatch)
+		if err != nil {
+			log.Error("Unable to get orphaned attachments", "err", err)
+			return
+		}
+		if len(attachments) == 0 {
+			break
+		}
+
+		ids := make([]uint, len(attachments))
+		keys := []string{}
+		for i, attachment := range attachments {
+			ids[i] = attachment.ID
+			keys = append(keys, attachment.BlobKey)
+			if attachment.ThumbnailKey != "" {
+				keys = append(keys, attachment.ThumbnailKey)
+			}
+		}
+
+		if err := s.Blobs.Delete(ctx, keys...); err != nil {
+			log.Error("Unable to delete attachment blobs", "err", err)
+			return
+		}
+		if err := s.Store.DeleteAttachments(ids); err != nil {
+			log.Error("Unable to delete orphaned attachments", "err", err)
+			return
+		}
+
+		total += len(attachments)
+		if len(attachments) < trashPurgeBatch {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info("Purged orphaned attachments", "count", total)
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"go-posts/server/controllers"
+	"go-posts/utils"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// How often the scheduler looks for due posts and how many posts it publishes
+// per transaction.
+var (
+	schedulerInterval = utils.GetEnvDuration("SCHEDULER_INTERVAL", 30*time.Second)
+	schedulerBatch    = utils.GetEnvInt("SCHEDULER_BATCH", 100)
+)
+
+// RunScheduler publishes, every schedulerInterval, the scheduled posts whose
+// publication time has come. Several replicas can run it at once, the storage
+// hands every due post to a single one of them. It never returns, so it is
+// meant to be started in its own goroutine.
+func (s *Server) RunScheduler() {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.publishScheduled()
+		<-ticker.C
+	}
+}
+
+func (s *Server) publishScheduled() {
+	for {
+		posts, err := s.Store.PublishScheduledPosts(time.Now(), schedulerBatch)
+		if err != nil {
+			log.Error("Unable to publish scheduled posts", "err", err)
+			return
+		}
+		if len(posts) == 0 {
+			return
+		}
+
+		log.Info("Published scheduled posts", "count", len(posts))
+		controllers.PublishedPosts(context.Background(), s.Store, s.Cache, s.Timelines, s.Events, posts...)
+
+		if len(posts) < schedulerBatch {
+			return
+		}
+	}
+}
@@ -2,8 +2,10 @@ package server
 
 import (
 	"fmt"
+	"go-posts/blobstore"
 	"go-posts/cache"
 	"go-posts/server/controllers"
+	"go-posts/server/middleware"
 	"go-posts/storage"
 
 	"github.com/charmbracelet/log"
@@ -11,16 +13,24 @@ import (
 )
 
 type Server struct {
-	Store  storage.Storage
-	Cache  *cache.RedisCache
-	Engine *gin.Engine
+	Store     storage.Storage
+	Cache     cache.Cache
+	Timelines cache.Timelines
+	Events    cache.Events
+	Stream    *controllers.StreamHub
+	Blobs     blobstore.BlobStore
+	Engine    *gin.Engine
 }
 
-func CreateService(store storage.Storage, cache *cache.RedisCache) *Server {
+func CreateService(store storage.Storage, cache cache.Backend, blobs blobstore.BlobStore) *Server {
 	return &Server{
-		Store:  store,
-		Cache:  cache,
-		Engine: gin.Default(),
+		Store:     store,
+		Cache:     cache,
+		Timelines: cache,
+		Events:    cache,
+		Stream:    controllers.NewStreamHub(cache),
+		Blobs:     blobs,
+		Engine:    gin.Default(),
 	}
 }
 
@@ -30,20 +40,63 @@ func (s *Server) SetupRoutes() {
 	s.Engine.GET("/posts/load", controllers.GetLoadState())
 
 	// Rabbitmq
-	s.Engine.GET("/posts/count", controlles.CountPosts(s.Store))
+	s.Engine.GET("/posts/count", controllers.CountPosts(s.Store))
+	s.Engine.POST("/posts/authors/invalidate", middleware.RequireInternal(), controllers.InvalidateAuthor(s.Cache))
+	s.Engine.POST("/posts/notifications/follow", middleware.RequireInternal(), controllers.NotifyFollow(s.Store, s.Timelines))
 
 	// Free ----
 	s.Engine.GET("/posts/latest", controllers.GetLatestPosts(s.Store, s.Cache))
 	s.Engine.GET("/posts/mostliked", controllers.GetMostLikedPosts(s.Store, s.Cache))
+	s.Engine.GET("/posts/revisions", controllers.GetPostRevisions(s.Store, s.Cache))
+	s.Engine.GET("/posts/search", controllers.SearchPosts(s.Store, s.Cache))
+	s.Engine.GET("/posts/tag/:tag", controllers.GetTagPosts(s.Store, s.Cache))
+	s.Engine.GET("/posts/tags/trending", controllers.GetTrendingTags(s.Store, s.Cache))
+	s.Engine.GET("/posts/stream", controllers.StreamPosts(s.Stream))
 
 	// Protected
 	s.Engine.GET("/posts/user", controllers.GetUsersPosts(s.Store, s.Cache))
-	s.Engine.POST("/posts/new", controllers.CreatePost(s.Store, s.Cache))
+	s.Engine.GET("/posts/timeline", controllers.GetTimeline(s.Store, s.Cache, s.Timelines))
+	s.Engine.POST("/posts/new", controllers.CreatePost(s.Store, s.Cache, s.Timelines, s.Events))
+	s.Engine.PATCH("/posts/edit", controllers.EditPost(s.Store, s.Cache))
 	s.Engine.DELETE("/posts/delete", controllers.DeletePost(s.Store, s.Cache))
 
-	//TODO Should be moved to likes
-	// s.Engine.PATCH("/posts/like", controllers.LikePost(s.Store, s.Cache))
-	// s.Engine.PATCH("/posts/unlike", controllers.UnlikePost(s.Store, s.Cache))
+	// Attachments ----
+	s.Engine.POST("/posts/attachments/new", controllers.UploadAttachment(s.Store, s.Blobs))
+	s.Engine.GET("/posts/attachments/:id", controllers.GetAttachment(s.Store, s.Blobs, false))
+	s.Engine.GET("/posts/attachments/:id/thumbnail", controllers.GetAttachment(s.Store, s.Blobs, true))
+
+	// Drafts ----
+	s.Engine.GET("/posts/drafts", controllers.GetDrafts(s.Store, s.Cache))
+	s.Engine.PATCH("/posts/drafts/edit", controllers.EditDraft(s.Store, s.Cache, s.Timelines, s.Events))
+
+	// Trash ----
+	s.Engine.GET("/posts/trash", controllers.GetTrash(s.Store, s.Cache))
+	s.Engine.POST("/posts/restore", controllers.RestorePost(s.Store, s.Cache))
+
+	// Reposts ----
+	s.Engine.POST("/posts/repost", controllers.Repost(s.Store, s.Cache, s.Timelines, s.Events))
+	s.Engine.DELETE("/posts/unrepost", controllers.Unrepost(s.Store, s.Cache))
+
+	// Likes ----
+	s.Engine.PATCH("/posts/like", controllers.LikePost(s.Store, s.Cache, s.Events))
+	s.Engine.PATCH("/posts/unlike", controllers.UnlikePost(s.Store, s.Cache, s.Events))
+	s.Engine.GET("/posts/liked", controllers.GetLikedPosts(s.Store, s.Cache))
+
+	// Bookmarks ----
+	s.Engine.POST("/posts/bookmark", controllers.BookmarkPost(s.Store, s.Cache))
+	s.Engine.DELETE("/posts/unbookmark", controllers.UnbookmarkPost(s.Store, s.Cache))
+	s.Engine.GET("/posts/bookmarks", controllers.GetBookmarkedPosts(s.Store, s.Cache))
+
+	// Notifications ----
+	s.Engine.GET("/posts/notifications", controllers.GetNotifications(s.Store, s.Cache))
+	s.Engine.GET("/posts/notifications/unread", controllers.CountUnreadNotifications(s.Store))
+	s.Engine.PATCH("/posts/notifications/read", controllers.MarkNotificationsRead(s.Store))
+
+	// Comments ----
+	s.Engine.GET("/posts/comments", controllers.GetComments(s.Store, s.Cache))
+	s.Engine.POST("/posts/comments/new", controllers.CreateComment(s.Store, s.Cache))
+	s.Engine.PATCH("/posts/comments/edit", controllers.EditComment(s.Store, s.Cache))
+	s.Engine.DELETE("/posts/comments/delete", controllers.DeleteComment(s.Store, s.Cache))
 }
 
 func (s *Server) Run(basePort int) {
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"context"
+	"go-posts/utils"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// streamRetry is how long the stream hub waits before subscribing again
+// after its subscription failed.
+var streamRetry = utils.GetEnvDuration("STREAM_RETRY", 5*time.Second)
+
+// RunStream relays the feed updates published by every replica to the
+// streams connected to this one, subscribing again whenever the subscription
+// is lost. It never returns, so it is meant to be started in its own
+// goroutine.
+func (s *Server) RunStream() {
+	for {
+		if err := s.Stream.Run(context.Background()); err != nil {
+			log.Error("Unable to subscribe to stream events", "err", err)
+		} else {
+			log.Error("Stream events subscription ended")
+		}
+		time.Sleep(streamRetry)
+	}
+}
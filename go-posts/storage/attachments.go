@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"go-posts/storage/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAttachmentUnavailable is returned when a post is created with attachments
+// that do not exist, belong to another user or are attached already.
+var ErrAttachmentUnavailable error = &kindError{msg: "attachment not found or already attached", kind: ErrNotFound}
+
+// attach hands the attachments over to the post. The caller must run it in
+// the transaction creating the post.
+func attach(tx *gorm.DB, post *models.Post, attachmentIDs []uint) error {
+	ids := uniqueIDs(attachmentIDs)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	res := tx.Model(&models.Attachment{}).
+		Where("id IN ? AND uploader_id = ? AND post_id IS NULL", ids, post.AuthorID).
+		Update("post_id", post.ID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected != int64(len(ids)) {
+		return ErrAttachmentUnavailable
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	res := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+func (store *PostgreStore) CreateAttachment(attachment *models.Attachment) error {
+	return translateError(store.Conn.Create(attachment).Error)
+}
+
+func (store *PostgreStore) GetAttachment(attachmentID uint) (models.Attachment, error) {
+	var attachment models.Attachment
+	err := store.Conn.First(&attachment, attachmentID).Error
+	return attachment, translateError(err)
+}
+
+// GetAttachments returns the attachments of the given posts grouped by post,
+// in upload order.
+func (store *PostgreStore) GetAttachments(postIDs []uint) (map[uint][]models.Attachment, error) {
+	byPost := make(map[uint][]models.Attachment)
+	if len(postIDs) == 0 {
+		return byPost, nil
+	}
+
+	var attachments []models.Attachment
+	err := store.Conn.Where("post_id IN ?", postIDs).Order("id asc").Find(&attachments).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	for _, attachment := range attachments {
+		byPost[*attachment.PostID] = append(byPost[*attachment.PostID], attachment)
+	}
+	return byPost, nil
+}
+
+// GetOrphanedAttachments returns up to limit attachments nobody can reach
+// anymore: uploads never attached to a post since uploadedBefore and
+// attachments of purged posts.
+func (store *PostgreStore) GetOrphanedAttachments(uploadedBefore time.Time, limit int) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := store.Conn.
+		Joins("LEFT JOIN posts ON posts.id = attachments.post_id").
+		Where("(attachments.post_id IS NULL AND attachments.created_at < ?) OR (attachments.post_id IS NOT NULL AND posts.id IS NULL)", uploadedBefore).
+		Order("attachments.id asc").
+		Limit(limit).
+		Find(&attachments).Error
+	return attachments, translateError(err)
+}
+
+func (store *PostgreStore) DeleteAttachments(attachmentIDs []uint) error {
+	if len(attachmentIDs) == 0 {
+		return nil
+	}
+	return translateError(store.Conn.Delete(&models.Attachment{}, attachmentIDs).Error)
+}
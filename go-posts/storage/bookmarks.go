@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"go-posts/storage/models"
+
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAlreadyBookmarked error = &kindError{msg: "post is already bookmarked", kind: ErrConflict}
+	ErrNotBookmarked     error = &kindError{msg: "post is not bookmarked", kind: ErrConflict}
+)
+
+// BookmarkPost saves the published post to the user's bookmarks.
+func (store *PostgreStore) BookmarkPost(userID uint, postID uint) error {
+	var post models.Post
+	if err := store.Conn.Scopes(published).Select("id").First(&post, postID).Error; err != nil {
+		return translateError(err)
+	}
+
+	res := store.Conn.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Bookmark{UserID: userID, PostID: postID})
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrAlreadyBookmarked
+	}
+	return nil
+}
+
+// UnbookmarkPost removes the post from the user's bookmarks.
+func (store *PostgreStore) UnbookmarkPost(userID uint, postID uint) error {
+	res := store.Conn.Where("user_id = ? AND post_id = ?", userID, postID).Delete(&models.Bookmark{})
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotBookmarked
+	}
+	return nil
+}
+
+// GetBookmarkedPostIDs reports which of the given posts are bookmarked by the
+// user.
+func (store *PostgreStore) GetBookmarkedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error) {
+	bookmarked := make(map[uint]bool)
+	if len(postIDs) == 0 {
+		return bookmarked, nil
+	}
+
+	var ids []uint
+	err := store.Conn.Model(&models.Bookmark{}).
+		Where("user_id = ? AND post_id IN ?", userID, postIDs).
+		Pluck("post_id", &ids).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	for _, id := range ids {
+		bookmarked[id] = true
+	}
+	return bookmarked, nil
+}
+
+// GetBookmarkedPosts returns the posts bookmarked by the user, most recently
+// saved first. Bookmarked posts which got deleted are left out.
+func (store *PostgreStore) GetBookmarkedPosts(limit int, offset int, userID uint) ([]models.Post, error) {
+	var posts []models.Post
+	err := store.Conn.
+		Joins("JOIN bookmarks ON bookmarks.post_id = posts.id").
+		Where("bookmarks.user_id = ?", userID).
+		Scopes(published).
+		Order("bookmarks.created_at desc, posts.id desc").
+		Limit(limit).Offset(offset).
+		Find(&posts).Error
+	return posts, translateError(err)
+}
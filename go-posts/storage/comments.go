@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"errors"
+	"go-posts/storage/models"
+
+	"gorm.io/gorm"
+)
+
+// MaxCommentDepth is the deepest level a reply can be nested at. Top level
+// comments have depth 0.
+const MaxCommentDepth = 4
+
+var (
+	ErrCommentTooDeep = errors.New("comment is nested too deep")
+	ErrParentMismatch = errors.New("parent comment belongs to another post")
+)
+
+// CreateComment saves the comment, resolving its depth and thread from the
+// parent comment, and increments the post's comments counter.
+func (store *PostgreStore) CreateComment(comment *models.Comment) error {
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		var post models.Post
+		if err := tx.Scopes(published).Select("id").First(&post, comment.PostID).Error; err != nil {
+			return err
+		}
+
+		comment.Depth = 0
+		comment.RootID = nil
+		if comment.ParentID != nil {
+			var parent models.Comment
+			if err := tx.First(&parent, *comment.ParentID).Error; err != nil {
+				return err
+			}
+			if parent.PostID != comment.PostID {
+				return ErrParentMismatch
+			}
+			if parent.Depth+1 > MaxCommentDepth {
+				return ErrCommentTooDeep
+			}
+
+			rootID := parent.ID
+			if parent.RootID != nil {
+				rootID = *parent.RootID
+			}
+			comment.RootID = &rootID
+			comment.Depth = parent.Depth + 1
+		}
+
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Post{}).Where("id = ?", comment.PostID).
+			UpdateColumn("comments_count", gorm.Expr("comments_count + 1")).Error
+	})
+	return translateError(err)
+}
+
+func (store *PostgreStore) GetComment(commentID uint) (models.Comment, error) {
+	var comment models.Comment
+	err := store.Conn.First(&comment, commentID).Error
+	return comment, translateError(err)
+}
+
+// GetCommentThreads returns a page of top level comments of the post, oldest
+// first, together with all of their replies.
+func (store *PostgreStore) GetCommentThreads(limit int, offset int, postID uint) ([]models.Comment, error) {
+	var roots []models.Comment
+	err := store.Conn.
+		Where("post_id = ? AND parent_id IS NULL", postID).
+		Order("created_at asc, id asc").
+		Limit(limit).Offset(offset).
+		Find(&roots).Error
+	if err != nil || len(roots) == 0 {
+		return roots, translateError(err)
+	}
+
+	rootIDs := make([]uint, len(roots))
+	for i, root := range roots {
+		rootIDs[i] = root.ID
+	}
+
+	var replies []models.Comment
+	err = store.Conn.
+		Where("root_id IN ?", rootIDs).
+		Order("created_at asc, id asc").
+		Find(&replies).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return append(roots, replies...), nil
+}
+
+func (store *PostgreStore) UpdateComment(commentID uint, body string) error {
+	res := store.Conn.Model(&models.Comment{}).Where("id = ?", commentID).Update("body", body)
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteComment deletes the comment together with all of its replies and
+// decrements the post's comments counter accordingly.
+func (store *PostgreStore) DeleteComment(commentID uint) error {
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		var comment models.Comment
+		if err := tx.First(&comment, commentID).Error; err != nil {
+			return err
+		}
+
+		ids := []uint{comment.ID}
+		for level := ids; len(level) > 0; {
+			var children []uint
+			err := tx.Model(&models.Comment{}).Where("parent_id IN ?", level).Pluck("id", &children).Error
+			if err != nil {
+				return err
+			}
+			ids = append(ids, children...)
+			level = children
+		}
+
+		res := tx.Delete(&models.Comment{}, ids)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		return tx.Model(&models.Post{}).Where("id = ?", comment.PostID).
+			UpdateColumn("comments_count", gorm.Expr("GREATEST(comments_count - ?, 0)", res.RowsAffected)).Error
+	})
+	return translateError(err)
+}
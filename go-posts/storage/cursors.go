@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"go-posts/storage/models"
+	"time"
+)
+
+// PostCursor is the position of the last post of a keyset page. Feeds ordered
+// by time use CreatedAt and ID, feeds ordered by likes use LikesCount and ID,
+// search results use Rank and ID.
+type PostCursor struct {
+	CreatedAt  time.Time
+	LikesCount uint
+	Rank       float32
+	ID         uint
+}
+
+// GetLatestPostsAfter returns up to limit posts ordered by (created_at, id)
+// descending that come after the cursor. A nil cursor starts from the newest.
+func (store *PostgreStore) GetLatestPostsAfter(limit int, after *PostCursor) ([]models.Post, error) {
+	var posts []models.Post
+	query := store.Conn.Scopes(published).Order("created_at desc, id desc").Limit(limit)
+	if after != nil {
+		query = query.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+	err := query.Find(&posts).Error
+	return posts, translateError(err)
+}
+
+// GetMostLikedPostsAfter returns up to limit posts ordered by (likes_count, id)
+// descending that come after the cursor.
+func (store *PostgreStore) GetMostLikedPostsAfter(limit int, after *PostCursor) ([]models.Post, error) {
+	var posts []models.Post
+	query := store.Conn.Scopes(published).Order("likes_count desc, id desc").Limit(limit)
+	if after != nil {
+		query = query.Where("(likes_count, id) < (?, ?)", after.LikesCount, after.ID)
+	}
+	err := query.Find(&posts).Error
+	return posts, translateError(err)
+}
+
+// GetUsersPostsAfter is GetLatestPostsAfter restricted to a single author.
+func (store *PostgreStore) GetUsersPostsAfter(limit int, after *PostCursor, authorID uint) ([]models.Post, error) {
+	var posts []models.Post
+	query := store.Conn.Scopes(published).Where("author_id = ?", authorID).Order("created_at desc, id desc").Limit(limit)
+	if after != nil {
+		query = query.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+	err := query.Find(&posts).Error
+	return posts, translateError(err)
+}
+
+// GetPostsByAuthorsAfter is GetLatestPostsAfter restricted to the given
+// authors.
+func (store *PostgreStore) GetPostsByAuthorsAfter(limit int, after *PostCursor, authorIDs []uint) ([]models.Post, error) {
+	var posts []models.Post
+	if len(authorIDs) == 0 {
+		return posts, nil
+	}
+
+	query := store.Conn.Scopes(published).Where("author_id IN ?", authorIDs).Order("created_at desc, id desc").Limit(limit)
+	if after != nil {
+		query = query.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+	err := query.Find(&posts).Error
+	return posts, translateError(err)
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"go-posts/storage/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetDrafts returns the author's drafts and scheduled posts, most recently
+// updated first.
+func (store *PostgreStore) GetDrafts(limit int, offset int, authorID uint) ([]models.Post, error) {
+	var posts []models.Post
+	err := store.Conn.
+		Where("author_id = ? AND status <> ?", authorID, models.PostPublished).
+		Order("updated_at desc, id desc").
+		Limit(limit).Offset(offset).
+		Find(&posts).Error
+	return posts, translateError(err)
+}
+
+// UpdateDraft replaces the content and the publication settings of a post
+// which is not published yet. Publishing it right away resets its CreatedAt.
+func (store *PostgreStore) UpdateDraft(postID uint, title string, body string, status string, publishAt *time.Time) (models.Post, error) {
+	var post models.Post
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status <> ?", models.PostPublished).
+			First(&post, postID).Error
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"title":      title,
+			"body":       body,
+			"status":     status,
+			"publish_at": publishAt,
+		}
+		if status == models.PostPublished {
+			updates["created_at"] = time.Now()
+			updates["publish_at"] = nil
+		}
+
+		if err := tx.Model(&post).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&post, postID).Error; err != nil {
+			return err
+		}
+		return updateRepostsCount(tx, post, "+")
+	})
+	return post, translateError(err)
+}
+
+// PublishScheduledPosts publishes up to limit scheduled posts whose
+// publication time is not after now and returns them. The rows are locked
+// with SKIP LOCKED, so replicas running it at the same time publish disjoint
+// sets of posts.
+func (store *PostgreStore) PublishScheduledPosts(now time.Time, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND publish_at <= ?", models.PostScheduled, now).
+			Order("publish_at asc, id asc").
+			Limit(limit).
+			Find(&posts).Error
+		if err != nil || len(posts) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(posts))
+		for i := range posts {
+			posts[i].Status = models.PostPublished
+			posts[i].CreatedAt = now
+			ids[i] = posts[i].ID
+		}
+
+		err = tx.Model(&models.Post{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":     models.PostPublished,
+			"created_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, post := range posts {
+			if err := updateRepostsCount(tx, post, "+"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return posts, nil
+}
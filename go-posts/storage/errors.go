@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Errors every Storage implementation reports its failures with, so callers
+// do not depend on the underlying database driver.
+var (
+	ErrNotFound = errors.New("record not found")
+	ErrConflict = errors.New("record conflicts with existing data")
+)
+
+// kindError is a specific error, which also matches the general kind of the
+// failure (ErrNotFound or ErrConflict) with errors.Is.
+type kindError struct {
+	msg  string
+	kind error
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+func (e *kindError) Unwrap() error { return e.kind }
+
+// translateError replaces the gorm errors with the storage ones.
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrConflict
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"go-posts/storage/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAlreadyLiked error = &kindError{msg: "post is already liked", kind: ErrConflict}
+	ErrNotLiked     error = &kindError{msg: "post is not liked", kind: ErrConflict}
+)
+
+// LikePost records a like of the post by the user and increments the post's
+// likes counter in the same transaction.
+func (store *PostgreStore) LikePost(userID uint, postID uint) error {
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		var post models.Post
+		if err := tx.Scopes(published).Select("id").First(&post, postID).Error; err != nil {
+			return err
+		}
+
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Like{UserID: userID, PostID: postID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrAlreadyLiked
+		}
+
+		return tx.Model(&models.Post{}).Where("id = ?", postID).
+			UpdateColumn("likes_count", gorm.Expr("likes_count + 1")).Error
+	})
+	return translateError(err)
+}
+
+// UnlikePost removes the user's like of the post and decrements the post's
+// likes counter in the same transaction.
+func (store *PostgreStore) UnlikePost(userID uint, postID uint) error {
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ? AND post_id = ?", userID, postID).Delete(&models.Like{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotLiked
+		}
+
+		return tx.Model(&models.Post{}).Where("id = ? AND likes_count > 0", postID).
+			UpdateColumn("likes_count", gorm.Expr("likes_count - 1")).Error
+	})
+	return translateError(err)
+}
+
+// GetLikedPostIDs reports which of the given posts are liked by the user.
+func (store *PostgreStore) GetLikedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error) {
+	liked := make(map[uint]bool)
+	if len(postIDs) == 0 {
+		return liked, nil
+	}
+
+	var ids []uint
+	err := store.Conn.Model(&models.Like{}).
+		Where("user_id = ? AND post_id IN ?", userID, postIDs).
+		Pluck("post_id", &ids).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	for _, id := range ids {
+		liked[id] = true
+	}
+	return liked, nil
+}
+
+// GetLikedPosts returns the posts liked by the user, most recently liked first.
+func (store *PostgreStore) GetLikedPosts(limit int, offset int, userID uint) ([]models.Post, error) {
+	var posts []models.Post
+	err := store.Conn.
+		Joins("JOIN likes ON likes.post_id = posts.id").
+		Where("likes.user_id = ?", userID).
+		Scopes(published).
+		Order("likes.created_at desc").
+		Limit(limit).Offset(offset).
+		Find(&posts).Error
+	return posts, translateError(err)
+}
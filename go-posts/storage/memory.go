@@ -0,0 +1,1216 @@
+package storage
+
+import (
+	"go-posts/storage/models"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStore is a thread-safe Storage kept in process memory. It follows the
+// ordering, pagination and uniqueness rules of PostgreStore, so the service
+// can run without a database. Nothing survives a restart and replicas do not
+// share data.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	lastPostID       uint
+	lastCommentID    uint
+	lastRevisionID   uint
+	lastTagID        uint
+	lastAttachmentID uint
+
+	lastNotificationID uint
+
+	posts     map[uint]*models.Post
+	likes     map[userPostKey]time.Time
+	bookmarks map[userPostKey]time.Time
+	comments  map[uint]*models.Comment
+	revisions map[uint][]models.PostRevision
+	tagIDs    map[string]uint
+	tagNames  map[uint]string
+	postTags  map[uint]map[uint]time.Time
+
+	attachments map[uint]*models.Attachment
+
+	notifications map[uint]*models.Notification
+}
+
+// userPostKey identifies a user's like or bookmark of a post.
+type userPostKey struct {
+	userID uint
+	postID uint
+}
+
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{}
+	store.CreateStorage()
+	return store
+}
+
+// CreateStorage resets the store to an empty state.
+func (store *MemoryStore) CreateStorage() {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.posts = make(map[uint]*models.Post)
+	store.likes = make(map[userPostKey]time.Time)
+	store.bookmarks = make(map[userPostKey]time.Time)
+	store.comments = make(map[uint]*models.Comment)
+	store.revisions = make(map[uint][]models.PostRevision)
+	store.tagIDs = make(map[string]uint)
+	store.tagNames = make(map[uint]string)
+	store.postTags = make(map[uint]map[uint]time.Time)
+	store.attachments = make(map[uint]*models.Attachment)
+	store.notifications = make(map[uint]*models.Notification)
+}
+
+// Migrate is a no-op, the memory store has no schema.
+func (store *MemoryStore) Migrate() {}
+
+// post returns the live (not deleted) post. The caller must hold the lock.
+func (store *MemoryStore) post(postID uint) (*models.Post, bool) {
+	post, ok := store.posts[postID]
+	if !ok || post.DeletedAt.Valid {
+		return nil, false
+	}
+	return post, true
+}
+
+// publishedPost returns the live post if it is published. The caller must
+// hold the lock.
+func (store *MemoryStore) publishedPost(postID uint) (*models.Post, bool) {
+	post, ok := store.post(postID)
+	if !ok || post.Status != models.PostPublished {
+		return nil, false
+	}
+	return post, true
+}
+
+// livePosts returns copies of the live published posts matching the filter.
+// The caller must hold the lock.
+func (store *MemoryStore) livePosts(filter func(post *models.Post) bool) []models.Post {
+	posts := []models.Post{}
+	for _, post := range store.posts {
+		if post.DeletedAt.Valid || post.Status != models.PostPublished || (filter != nil && !filter(post)) {
+			continue
+		}
+		posts = append(posts, *post)
+	}
+	return posts
+}
+
+func sortLatest(posts []models.Post) {
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+}
+
+func sortMostLiked(posts []models.Post) {
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].LikesCount != posts[j].LikesCount {
+			return posts[i].LikesCount > posts[j].LikesCount
+		}
+		return posts[i].ID > posts[j].ID
+	})
+}
+
+// paginate returns the page of items at offset, like LIMIT/OFFSET does.
+func paginate[T any](items []T, limit int, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// latestAfter keeps the posts that come after the cursor in the latest order.
+func latestAfter(posts []models.Post, after *PostCursor) []models.Post {
+	if after == nil {
+		return posts
+	}
+	res := []models.Post{}
+	for _, post := range posts {
+		if post.CreatedAt.Before(after.CreatedAt) || (post.CreatedAt.Equal(after.CreatedAt) && post.ID < after.ID) {
+			res = append(res, post)
+		}
+	}
+	return res
+}
+
+func (store *MemoryStore) CreatePost(post *models.Post, attachmentIDs []uint) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if err := store.resolveOriginal(post); err != nil {
+		return err
+	}
+	if post.RepostOfID != nil && store.repostOf(post.AuthorID, *post.RepostOfID) != nil {
+		return ErrAlreadyReposted
+	}
+
+	ids := uniqueIDs(attachmentIDs)
+	for _, id := range ids {
+		attachment, ok := store.attachments[id]
+		if !ok || attachment.UploaderID != post.AuthorID || attachment.PostID != nil {
+			return ErrAttachmentUnavailable
+		}
+	}
+
+	store.lastPostID++
+	now := time.Now()
+	post.ID = store.lastPostID
+	post.CreatedAt = now
+	post.UpdatedAt = now
+	if post.Status == "" {
+		post.Status = models.PostPublished
+	}
+
+	stored := *post
+	store.posts[post.ID] = &stored
+
+	for _, id := range ids {
+		postID := post.ID
+		store.attachments[id].PostID = &postID
+	}
+	store.updateRepostsCount(post, 1)
+	return nil
+}
+
+func (store *MemoryStore) GetLatestPosts(limit int, offset int) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	posts := store.livePosts(nil)
+	sortLatest(posts)
+	return paginate(posts, limit, offset), nil
+}
+
+func (store *MemoryStore) GetMostLikedPosts(limit int, offset int) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	posts := store.livePosts(nil)
+	sortMostLiked(posts)
+	return paginate(posts, limit, offset), nil
+}
+
+func (store *MemoryStore) GetUsersPosts(limit int, offset int, authorID uint) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	posts := store.livePosts(func(post *models.Post) bool { return post.AuthorID == authorID })
+	sortLatest(posts)
+	return paginate(posts, limit, offset), nil
+}
+
+func (store *MemoryStore) GetLatestPostsAfter(limit int, after *PostCursor) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	posts := store.livePosts(nil)
+	sortLatest(posts)
+	return paginate(latestAfter(posts, after), limit, 0), nil
+}
+
+func (store *MemoryStore) GetMostLikedPostsAfter(limit int, after *PostCursor) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	posts := store.livePosts(func(post *models.Post) bool {
+		return after == nil || post.LikesCount < after.LikesCount ||
+			(post.LikesCount == after.LikesCount && post.ID < after.ID)
+	})
+	sortMostLiked(posts)
+	return paginate(posts, limit, 0), nil
+}
+
+func (store *MemoryStore) GetUsersPostsAfter(limit int, after *PostCursor, authorID uint) ([]models.Post, error) {
+	return store.GetPostsByAuthorsAfter(limit, after, []uint{authorID})
+}
+
+func (store *MemoryStore) GetPostsByAuthorsAfter(limit int, after *PostCursor, authorIDs []uint) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	authors := make(map[uint]bool, len(authorIDs))
+	for _, id := range authorIDs {
+		authors[id] = true
+	}
+
+	posts := store.livePosts(func(post *models.Post) bool { return authors[post.AuthorID] })
+	sortLatest(posts)
+	return paginate(latestAfter(posts, after), limit, 0), nil
+}
+
+func (store *MemoryStore) GetPost(postID uint) (models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	post, ok := store.post(postID)
+	if !ok {
+		return models.Post{}, ErrNotFound
+	}
+	return *post, nil
+}
+
+func (store *MemoryStore) GetPostsByIDs(postIDs []uint) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	posts := []models.Post{}
+	for _, id := range postIDs {
+		if post, ok := store.publishedPost(id); ok {
+			posts = append(posts, *post)
+		}
+	}
+	return posts, nil
+}
+
+func (store *MemoryStore) CountPosts(userID uint) (int64, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return int64(len(store.livePosts(func(post *models.Post) bool { return post.AuthorID == userID }))), nil
+}
+
+func (store *MemoryStore) DeletePost(postID uint) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	post, ok := store.post(postID)
+	if !ok {
+		return ErrNotFound
+	}
+	post.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	store.updateRepostsCount(post, -1)
+	return nil
+}
+
+func (store *MemoryStore) UpdatePost(postID uint, title string, body string) (models.Post, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	post, ok := store.post(postID)
+	if !ok {
+		return models.Post{}, ErrNotFound
+	}
+
+	now := time.Now()
+	store.lastRevisionID++
+	revision := models.PostRevision{PostID: postID, Title: post.Title, Body: post.Body}
+	revision.ID = store.lastRevisionID
+	revision.CreatedAt = now
+	revision.UpdatedAt = now
+	store.revisions[postID] = append(store.revisions[postID], revision)
+
+	post.Title = title
+	post.Body = body
+	post.EditedAt = &now
+	post.UpdatedAt = now
+	return *post, nil
+}
+
+func (store *MemoryStore) GetPostRevisions(postID uint) ([]models.PostRevision, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	stored := store.revisions[postID]
+	revisions := make([]models.PostRevision, len(stored))
+	for i := range stored {
+		revisions[i] = stored[len(stored)-1-i]
+	}
+	return revisions, nil
+}
+
+func (store *MemoryStore) LikePost(userID uint, postID uint) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	post, ok := store.publishedPost(postID)
+	if !ok {
+		return ErrNotFound
+	}
+
+	key := userPostKey{userID: userID, postID: postID}
+	if _, liked := store.likes[key]; liked {
+		return ErrAlreadyLiked
+	}
+
+	store.likes[key] = time.Now()
+	post.LikesCount++
+	return nil
+}
+
+func (store *MemoryStore) UnlikePost(userID uint, postID uint) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := userPostKey{userID: userID, postID: postID}
+	if _, liked := store.likes[key]; !liked {
+		return ErrNotLiked
+	}
+
+	delete(store.likes, key)
+	if post, ok := store.post(postID); ok && post.LikesCount > 0 {
+		post.LikesCount--
+	}
+	return nil
+}
+
+func (store *MemoryStore) GetLikedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	liked := make(map[uint]bool)
+	for _, postID := range postIDs {
+		if _, ok := store.likes[userPostKey{userID: userID, postID: postID}]; ok {
+			liked[postID] = true
+		}
+	}
+	return liked, nil
+}
+
+func (store *MemoryStore) GetLikedPosts(limit int, offset int, userID uint) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	likedAt := map[uint]time.Time{}
+	posts := store.livePosts(func(post *models.Post) bool {
+		at, ok := store.likes[userPostKey{userID: userID, postID: post.ID}]
+		likedAt[post.ID] = at
+		return ok
+	})
+	sort.Slice(posts, func(i, j int) bool {
+		return likedAt[posts[i].ID].After(likedAt[posts[j].ID])
+	})
+	return paginate(posts, limit, offset), nil
+}
+
+func (store *MemoryStore) BookmarkPost(userID uint, postID uint) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, ok := store.publishedPost(postID); !ok {
+		return ErrNotFound
+	}
+
+	key := userPostKey{userID: userID, postID: postID}
+	if _, bookmarked := store.bookmarks[key]; bookmarked {
+		return ErrAlreadyBookmarked
+	}
+
+	store.bookmarks[key] = time.Now()
+	return nil
+}
+
+func (store *MemoryStore) UnbookmarkPost(userID uint, postID uint) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := userPostKey{userID: userID, postID: postID}
+	if _, bookmarked := store.bookmarks[key]; !bookmarked {
+		return ErrNotBookmarked
+	}
+
+	delete(store.bookmarks, key)
+	return nil
+}
+
+func (store *MemoryStore) GetBookmarkedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	bookmarked := make(map[uint]bool)
+	for _, postID := range postIDs {
+		if _, ok := store.bookmarks[userPostKey{userID: userID, postID: postID}]; ok {
+			bookmarked[postID] = true
+		}
+	}
+	return bookmarked, nil
+}
+
+func (store *MemoryStore) GetBookmarkedPosts(limit int, offset int, userID uint) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	savedAt := map[uint]time.Time{}
+	posts := store.livePosts(func(post *models.Post) bool {
+		at, ok := store.bookmarks[userPostKey{userID: userID, postID: post.ID}]
+		savedAt[post.ID] = at
+		return ok
+	})
+	sort.Slice(posts, func(i, j int) bool {
+		return savedAt[posts[i].ID].After(savedAt[posts[j].ID])
+	})
+	return paginate(posts, limit, offset), nil
+}
+
+func (store *MemoryStore) CreateComment(comment *models.Comment) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	post, ok := store.publishedPost(comment.PostID)
+	if !ok {
+		return ErrNotFound
+	}
+
+	comment.Depth = 0
+	comment.RootID = nil
+	if comment.ParentID != nil {
+		parent, ok := store.comments[*comment.ParentID]
+		if !ok {
+			return ErrNotFound
+		}
+		if parent.PostID != comment.PostID {
+			return ErrParentMismatch
+		}
+		if parent.Depth+1 > MaxCommentDepth {
+			return ErrCommentTooDeep
+		}
+
+		rootID := parent.ID
+		if parent.RootID != nil {
+			rootID = *parent.RootID
+		}
+		comment.RootID = &rootID
+		comment.Depth = parent.Depth + 1
+	}
+
+	store.lastCommentID++
+	now := time.Now()
+	comment.ID = store.lastCommentID
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+
+	stored := *comment
+	store.comments[comment.ID] = &stored
+	post.CommentsCount++
+	return nil
+}
+
+func (store *MemoryStore) GetComment(commentID uint) (models.Comment, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	comment, ok := store.comments[commentID]
+	if !ok {
+		return models.Comment{}, ErrNotFound
+	}
+	return *comment, nil
+}
+
+func sortOldest(comments []models.Comment) {
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return comments[i].ID < comments[j].ID
+	})
+}
+
+func (store *MemoryStore) GetCommentThreads(limit int, offset int, postID uint) ([]models.Comment, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	roots := []models.Comment{}
+	for _, comment := range store.comments {
+		if comment.PostID == postID && comment.ParentID == nil {
+			roots = append(roots, *comment)
+		}
+	}
+	sortOldest(roots)
+	roots = paginate(roots, limit, offset)
+
+	inPage := make(map[uint]bool, len(roots))
+	for _, root := range roots {
+		inPage[root.ID] = true
+	}
+
+	replies := []models.Comment{}
+	for _, comment := range store.comments {
+		if comment.RootID != nil && inPage[*comment.RootID] {
+			replies = append(replies, *comment)
+		}
+	}
+	sortOldest(replies)
+
+	return append(roots, replies...), nil
+}
+
+func (store *MemoryStore) UpdateComment(commentID uint, body string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	comment, ok := store.comments[commentID]
+	if !ok {
+		return ErrNotFound
+	}
+	comment.Body = body
+	comment.UpdatedAt = time.Now()
+	return nil
+}
+
+func (store *MemoryStore) DeleteComment(commentID uint) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	comment, ok := store.comments[commentID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	deleted := map[uint]bool{commentID: true}
+	for changed := true; changed; {
+		changed = false
+		for id, reply := range store.comments {
+			if !deleted[id] && reply.ParentID != nil && deleted[*reply.ParentID] {
+				deleted[id] = true
+				changed = true
+			}
+		}
+	}
+
+	for id := range deleted {
+		delete(store.comments, id)
+	}
+
+	if post, ok := store.posts[comment.PostID]; ok {
+		if post.CommentsCount > uint(len(deleted)) {
+			post.CommentsCount -= uint(len(deleted))
+		} else {
+			post.CommentsCount = 0
+		}
+	}
+	return nil
+}
+
+func (store *MemoryStore) SetPostTags(postID uint, tags []string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	current := store.postTags[postID]
+	next := make(map[uint]time.Time, len(tags))
+	for _, name := range tags {
+		tagID, ok := store.tagIDs[name]
+		if !ok {
+			store.lastTagID++
+			tagID = store.lastTagID
+			store.tagIDs[name] = tagID
+			store.tagNames[tagID] = name
+		}
+
+		if at, ok := current[tagID]; ok {
+			next[tagID] = at
+		} else {
+			next[tagID] = time.Now()
+		}
+	}
+
+	store.postTags[postID] = next
+	return nil
+}
+
+func (store *MemoryStore) GetPostsByTag(limit int, offset int, tag string) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	tagID, ok := store.tagIDs[tag]
+	if !ok {
+		return []models.Post{}, nil
+	}
+
+	posts := store.livePosts(func(post *models.Post) bool {
+		_, tagged := store.postTags[post.ID][tagID]
+		return tagged
+	})
+	sortLatest(posts)
+	return paginate(posts, limit, offset), nil
+}
+
+func (store *MemoryStore) GetTrendingTags(limit int, since time.Time) ([]TagCount, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	counts := map[string]int64{}
+	for postID, tags := range store.postTags {
+		if _, ok := store.publishedPost(postID); !ok {
+			continue
+		}
+		for tagID, at := range tags {
+			if !at.Before(since) {
+				counts[store.tagNames[tagID]]++
+			}
+		}
+	}
+
+	res := make([]TagCount, 0, len(counts))
+	for name, posts := range counts {
+		res = append(res, TagCount{Name: name, Posts: posts})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Posts != res[j].Posts {
+			return res[i].Posts > res[j].Posts
+		}
+		return res[i].Name < res[j].Name
+	})
+	return paginate(res, limit, 0), nil
+}
+
+var wordRegexp = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// SearchPosts approximates the Postgres full-text search: every term not
+// prefixed with "-" has to start a word of the title or body, title matches
+// rank higher than body matches. Stemming is emulated by prefix matching.
+func (store *MemoryStore) SearchPosts(query SearchQuery) ([]SearchResult, error) {
+	include, exclude := parseSearchTerms(query.Text)
+	if len(include) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	store.mu.RLock()
+	posts := store.livePosts(func(post *models.Post) bool {
+		return (query.AuthorID == 0 || post.AuthorID == query.AuthorID) &&
+			(query.From == nil || !post.CreatedAt.Before(*query.From)) &&
+			(query.To == nil || post.CreatedAt.Before(*query.To))
+	})
+	store.mu.RUnlock()
+
+	results := []SearchResult{}
+	for _, post := range posts {
+		titleWords := wordRegexp.FindAllString(strings.ToLower(post.Title), -1)
+		bodyWords := wordRegexp.FindAllString(strings.ToLower(post.Body), -1)
+
+		if countMatches(titleWords, exclude)+countMatches(bodyWords, exclude) > 0 {
+			continue
+		}
+
+		var rank float32
+		matched := true
+		for _, term := range include {
+			inTitle := countMatches(titleWords, []string{term})
+			inBody := countMatches(bodyWords, []string{term})
+			if inTitle+inBody == 0 {
+				matched = false
+				break
+			}
+			rank += float32(inTitle) + 0.4*float32(inBody)
+		}
+		if !matched {
+			continue
+		}
+		rank /= float32(1 + len(titleWords) + len(bodyWords))
+
+		if query.After != nil && (rank > query.After.Rank || (rank == query.After.Rank && post.ID >= query.After.ID)) {
+			continue
+		}
+
+		results = append(results, SearchResult{
+			Post:         post,
+			Rank:         rank,
+			TitleSnippet: highlight(post.Title, include),
+			BodySnippet:  highlight(post.Body, include),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].ID > results[j].ID
+	})
+	return paginate(results, query.Limit, 0), nil
+}
+
+// parseSearchTerms splits the query into required and excluded lowercase
+// terms. Quotes and the "or" operator are ignored.
+func parseSearchTerms(text string) ([]string, []string) {
+	include, exclude := []string{}, []string{}
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		negated := strings.HasPrefix(field, "-")
+		for _, word := range wordRegexp.FindAllString(field, -1) {
+			switch {
+			case word == "or":
+			case negated:
+				exclude = append(exclude, word)
+			default:
+				include = append(include, word)
+			}
+		}
+	}
+	return include, exclude
+}
+
+func countMatches(words []string, terms []string) int {
+	n := 0
+	for _, word := range words {
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// highlight escapes the text and wraps the words starting with one of the
+// terms in <mark> tags, like SearchPosts does with ts_headline.
+func highlight(text string, terms []string) string {
+	return markSnippet(wordRegexp.ReplaceAllStringFunc(stripSnippetDelimiters(text), func(word string) string {
+		lower := strings.ToLower(word)
+		for _, term := range terms {
+			if strings.HasPrefix(lower, term) {
+				return snippetStart + word + snippetStop
+			}
+		}
+		return word
+	}))
+}
+
+func (store *MemoryStore) GetDeletedPosts(limit int, offset int, authorID uint) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	posts := []models.Post{}
+	for _, post := range store.posts {
+		if post.DeletedAt.Valid && post.AuthorID == authorID {
+			posts = append(posts, *post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].DeletedAt.Time.Equal(posts[j].DeletedAt.Time) {
+			return posts[i].DeletedAt.Time.After(posts[j].DeletedAt.Time)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	return paginate(posts, limit, offset), nil
+}
+
+func (store *MemoryStore) GetDeletedPost(postID uint) (models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	post, ok := store.posts[postID]
+	if !ok || !post.DeletedAt.Valid {
+		return models.Post{}, ErrNotFound
+	}
+	return *post, nil
+}
+
+func (store *MemoryStore) RestorePost(postID uint) (models.Post, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	post, ok := store.posts[postID]
+	if !ok || !post.DeletedAt.Valid {
+		return models.Post{}, ErrNotFound
+	}
+	if post.RepostOfID != nil && store.repostOf(post.AuthorID, *post.RepostOfID) != nil {
+		return models.Post{}, ErrConflict
+	}
+	post.DeletedAt = gorm.DeletedAt{}
+	store.updateRepostsCount(post, 1)
+	return *post, nil
+}
+
+func (store *MemoryStore) PurgeDeletedPosts(deletedBefore time.Time, limit int) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	expired := []models.Post{}
+	for _, post := range store.posts {
+		if post.DeletedAt.Valid && post.DeletedAt.Time.Before(deletedBefore) {
+			expired = append(expired, *post)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].DeletedAt.Time.Before(expired[j].DeletedAt.Time)
+	})
+	expired = paginate(expired, limit, 0)
+
+	purged := make(map[uint]bool, len(expired))
+	for _, post := range expired {
+		purged[post.ID] = true
+	}
+	for id, post := range store.posts {
+		if post.RepostOfID != nil && purged[*post.RepostOfID] {
+			purged[id] = true
+		}
+	}
+	store.deletePosts(purged)
+	return int64(len(expired)), nil
+}
+
+// deletePosts deletes the posts for good, together with everything pointing
+// at them. The caller must hold the lock.
+func (store *MemoryStore) deletePosts(ids map[uint]bool) {
+	for id := range ids {
+		delete(store.posts, id)
+		delete(store.revisions, id)
+		delete(store.postTags, id)
+	}
+	for key := range store.likes {
+		if _, ok := store.posts[key.postID]; !ok {
+			delete(store.likes, key)
+		}
+	}
+	for key := range store.bookmarks {
+		if _, ok := store.posts[key.postID]; !ok {
+			delete(store.bookmarks, key)
+		}
+	}
+	for id, notification := range store.notifications {
+		if notification.PostID == nil {
+			continue
+		}
+		if _, ok := store.posts[*notification.PostID]; !ok {
+			delete(store.notifications, id)
+		}
+	}
+	for id, comment := range store.comments {
+		if _, ok := store.posts[comment.PostID]; !ok {
+			delete(store.comments, id)
+		}
+	}
+}
+
+func (store *MemoryStore) GetDrafts(limit int, offset int, authorID uint) ([]models.Post, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	posts := []models.Post{}
+	for _, post := range store.posts {
+		if !post.DeletedAt.Valid && post.AuthorID == authorID && post.Status != models.PostPublished {
+			posts = append(posts, *post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].UpdatedAt.Equal(posts[j].UpdatedAt) {
+			return posts[i].UpdatedAt.After(posts[j].UpdatedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	return paginate(posts, limit, offset), nil
+}
+
+func (store *MemoryStore) UpdateDraft(postID uint, title string, body string, status string, publishAt *time.Time) (models.Post, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	post, ok := store.post(postID)
+	if !ok || post.Status == models.PostPublished {
+		return models.Post{}, ErrNotFound
+	}
+
+	now := time.Now()
+	post.Title = title
+	post.Body = body
+	post.Status = status
+	post.PublishAt = publishAt
+	post.UpdatedAt = now
+	if status == models.PostPublished {
+		post.CreatedAt = now
+		post.PublishAt = nil
+		store.updateRepostsCount(post, 1)
+	}
+	return *post, nil
+}
+
+func (store *MemoryStore) PublishScheduledPosts(now time.Time, limit int) ([]models.Post, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	due := []*models.Post{}
+	for _, post := range store.posts {
+		if !post.DeletedAt.Valid && post.Status == models.PostScheduled && post.PublishAt != nil && !post.PublishAt.After(now) {
+			due = append(due, post)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].PublishAt.Equal(*due[j].PublishAt) {
+			return due[i].PublishAt.Before(*due[j].PublishAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	due = paginate(due, limit, 0)
+
+	posts := make([]models.Post, len(due))
+	for i, post := range due {
+		post.Status = models.PostPublished
+		post.CreatedAt = now
+		store.updateRepostsCount(post, 1)
+		posts[i] = *post
+	}
+	return posts, nil
+}
+
+func (store *MemoryStore) CreateAttachment(attachment *models.Attachment) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.lastAttachmentID++
+	attachment.ID = store.lastAttachmentID
+	attachment.CreatedAt = time.Now()
+
+	stored := *attachment
+	store.attachments[attachment.ID] = &stored
+	return nil
+}
+
+func (store *MemoryStore) GetAttachment(attachmentID uint) (models.Attachment, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	attachment, ok := store.attachments[attachmentID]
+	if !ok {
+		return models.Attachment{}, ErrNotFound
+	}
+	return *attachment, nil
+}
+
+func (store *MemoryStore) GetAttachments(postIDs []uint) (map[uint][]models.Attachment, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	wanted := make(map[uint]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+
+	byPost := make(map[uint][]models.Attachment)
+	for _, attachment := range store.attachments {
+		if attachment.PostID != nil && wanted[*attachment.PostID] {
+			byPost[*attachment.PostID] = append(byPost[*attachment.PostID], *attachment)
+		}
+	}
+	for _, attachments := range byPost {
+		sort.Slice(attachments, func(i, j int) bool { return attachments[i].ID < attachments[j].ID })
+	}
+	return byPost, nil
+}
+
+func (store *MemoryStore) GetOrphanedAttachments(uploadedBefore time.Time, limit int) ([]models.Attachment, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	orphaned := []models.Attachment{}
+	for _, attachment := range store.attachments {
+		if attachment.PostID == nil {
+			if attachment.CreatedAt.Before(uploadedBefore) {
+				orphaned = append(orphaned, *attachment)
+			}
+		} else if _, ok := store.posts[*attachment.PostID]; !ok {
+			orphaned = append(orphaned, *attachment)
+		}
+	}
+	sort.Slice(orphaned, func(i, j int) bool { return orphaned[i].ID < orphaned[j].ID })
+	return paginate(orphaned, limit, 0), nil
+}
+
+func (store *MemoryStore) DeleteAttachments(attachmentIDs []uint) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, id := range attachmentIDs {
+		delete(store.attachments, id)
+	}
+	return nil
+}
+
+// resolveOriginal mirrors the Postgres resolveOriginal. The caller must hold
+// the lock.
+func (store *MemoryStore) resolveOriginal(post *models.Post) error {
+	originalID := post.OriginalID()
+	if originalID == nil {
+		return nil
+	}
+
+	original, ok := store.publishedPost(*originalID)
+	if !ok {
+		return ErrNotFound
+	}
+	if original.RepostOfID == nil {
+		return nil
+	}
+
+	shared := *original.RepostOfID
+	if _, ok := store.publishedPost(shared); !ok {
+		return ErrNotFound
+	}
+	if post.RepostOfID != nil {
+		post.RepostOfID = &shared
+	} else {
+		post.QuoteOfID = &shared
+	}
+	return nil
+}
+
+// repostOf returns the user's live repost of the post. The caller must hold
+// the lock.
+func (store *MemoryStore) repostOf(userID uint, postID uint) *models.Post {
+	for _, post := range store.posts {
+		if !post.DeletedAt.Valid && post.AuthorID == userID && post.RepostOfID != nil && *post.RepostOfID == postID {
+			return post
+		}
+	}
+	return nil
+}
+
+// updateRepostsCount adds delta to the counter of the post's original. The
+// caller must hold the lock.
+func (store *MemoryStore) updateRepostsCount(post *models.Post, delta int) {
+	originalID := post.OriginalID()
+	if originalID == nil || post.Status != models.PostPublished {
+		return
+	}
+
+	original, ok := store.posts[*originalID]
+	if !ok {
+		return
+	}
+	if delta > 0 {
+		original.RepostsCount++
+	} else if original.RepostsCount > 0 {
+		original.RepostsCount--
+	}
+}
+
+func (store *MemoryStore) DeleteRepost(userID uint, postID uint) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	original, ok := store.posts[postID]
+	if !ok {
+		return ErrNotFound
+	}
+	if original.RepostOfID != nil {
+		postID = *original.RepostOfID
+	}
+
+	repost := store.repostOf(userID, postID)
+	if repost == nil {
+		return ErrNotReposted
+	}
+
+	store.deletePosts(map[uint]bool{repost.ID: true})
+	store.updateRepostsCount(repost, -1)
+	return nil
+}
+
+func (store *MemoryStore) GetRepostedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	reposted := make(map[uint]bool)
+	for _, postID := range postIDs {
+		if store.repostOf(userID, postID) != nil {
+			reposted[postID] = true
+		}
+	}
+	return reposted, nil
+}
+
+// notified reports whether the notification repeats a like or a follow the
+// user was already notified of. The caller must hold the lock.
+func (store *MemoryStore) notified(notification models.Notification) bool {
+	if notification.Type != models.NotificationLike && notification.Type != models.NotificationFollow {
+		return false
+	}
+
+	for _, existing := range store.notifications {
+		if existing.UserID != notification.UserID || existing.ActorID != notification.ActorID || existing.Type != notification.Type {
+			continue
+		}
+		if notification.Type == models.NotificationFollow ||
+			(existing.PostID != nil && notification.PostID != nil && *existing.PostID == *notification.PostID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (store *MemoryStore) CreateNotifications(notifications []models.Notification) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for i := range notifications {
+		if store.notified(notifications[i]) {
+			continue
+		}
+
+		store.lastNotificationID++
+		notifications[i].ID = store.lastNotificationID
+		notifications[i].CreatedAt = time.Now()
+
+		stored := notifications[i]
+		store.notifications[stored.ID] = &stored
+	}
+	return nil
+}
+
+func (store *MemoryStore) GetNotifications(limit int, offset int, userID uint) ([]models.Notification, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	notifications := []models.Notification{}
+	for _, notification := range store.notifications {
+		if notification.UserID == userID {
+			notifications = append(notifications, *notification)
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		if !notifications[i].CreatedAt.Equal(notifications[j].CreatedAt) {
+			return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+		}
+		return notifications[i].ID > notifications[j].ID
+	})
+	return paginate(notifications, limit, offset), nil
+}
+
+func (store *MemoryStore) CountUnreadNotifications(userID uint) (int64, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var count int64
+	for _, notification := range store.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (store *MemoryStore) MarkNotificationsRead(userID uint, notificationIDs []uint) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	ids := map[uint]bool{}
+	for _, id := range notificationIDs {
+		ids[id] = true
+	}
+
+	now := time.Now()
+	var marked int64
+	for _, notification := range store.notifications {
+		if notification.UserID != userID || notification.ReadAt != nil {
+			continue
+		}
+		if len(ids) > 0 && !ids[notification.ID] {
+			continue
+		}
+		notification.ReadAt = &now
+		marked++
+	}
+	return marked, nil
+}
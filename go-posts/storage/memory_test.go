@@ -0,0 +1,379 @@
+package storage
+
+import (
+	"errors"
+	"go-posts/storage/models"
+	"testing"
+	"time"
+)
+
+// createPosts saves a published post per author and returns their ids in
+// creation order.
+func createPosts(t *testing.T, store *MemoryStore, authorIDs ...uint) []uint {
+	t.Helper()
+	ids := make([]uint, 0, len(authorIDs))
+	for _, authorID := range authorIDs {
+		post := models.Post{AuthorID: authorID, Title: "title", Body: "body"}
+		if err := store.CreatePost(&post, nil); err != nil {
+			t.Fatalf("CreatePost() = %v", err)
+		}
+		ids = append(ids, post.ID)
+	}
+	return ids
+}
+
+func postIDs(posts []models.Post) []uint {
+	ids := make([]uint, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
+
+func equalIDs(a []uint, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMemoryStoreCursorOrdering(t *testing.T) {
+	store := NewMemoryStore()
+	createPosts(t, store, 1, 2, 1, 2, 1)
+
+	// posts 2 to 4 share their creation time, so the id breaks the tie
+	base := time.Now().Add(-time.Hour)
+	createdAt := map[uint]time.Time{1: base, 2: base.Add(time.Second), 3: base.Add(time.Second), 4: base.Add(time.Second), 5: base.Add(2 * time.Second)}
+	for id, at := range createdAt {
+		store.posts[id].CreatedAt = at
+	}
+	store.posts[1].LikesCount = 3
+	store.posts[3].LikesCount = 3
+	store.posts[5].LikesCount = 1
+
+	cursor := func(id uint) *PostCursor {
+		post := store.posts[id]
+		return &PostCursor{CreatedAt: post.CreatedAt, LikesCount: post.LikesCount, ID: post.ID}
+	}
+
+	tests := []struct {
+		name  string
+		fetch func() ([]models.Post, error)
+		want  []uint
+	}{
+		{
+			name:  "latest from the start",
+			fetch: func() ([]models.Post, error) { return store.GetLatestPostsAfter(10, nil) },
+			want:  []uint{5, 4, 3, 2, 1},
+		},
+		{
+			name:  "latest after a tied post",
+			fetch: func() ([]models.Post, error) { return store.GetLatestPostsAfter(10, cursor(4)) },
+			want:  []uint{3, 2, 1},
+		},
+		{
+			name:  "latest limited",
+			fetch: func() ([]models.Post, error) { return store.GetLatestPostsAfter(2, cursor(5)) },
+			want:  []uint{4, 3},
+		},
+		{
+			name:  "latest after the last post",
+			fetch: func() ([]models.Post, error) { return store.GetLatestPostsAfter(10, cursor(1)) },
+			want:  []uint{},
+		},
+		{
+			name:  "most liked from the start",
+			fetch: func() ([]models.Post, error) { return store.GetMostLikedPostsAfter(10, nil) },
+			want:  []uint{3, 1, 5, 4, 2},
+		},
+		{
+			name:  "most liked after a tied post",
+			fetch: func() ([]models.Post, error) { return store.GetMostLikedPostsAfter(10, cursor(3)) },
+			want:  []uint{1, 5, 4, 2},
+		},
+		{
+			name:  "users posts",
+			fetch: func() ([]models.Post, error) { return store.GetUsersPostsAfter(10, cursor(5), 1) },
+			want:  []uint{3, 1},
+		},
+		{
+			name:  "posts by authors",
+			fetch: func() ([]models.Post, error) { return store.GetPostsByAuthorsAfter(10, cursor(3), []uint{1, 2}) },
+			want:  []uint{2, 1},
+		},
+	}
+	for _, tt := range tests {
+		posts, err := tt.fetch()
+		if err != nil {
+			t.Fatalf("%v: %v", tt.name, err)
+		}
+		if got := postIDs(posts); !equalIDs(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreCursorPagesEveryPostOnce(t *testing.T) {
+	store := NewMemoryStore()
+	created := createPosts(t, store, 1, 1, 1, 1, 1, 1, 1)
+	at := time.Now()
+	for _, post := range store.posts {
+		post.CreatedAt = at
+	}
+
+	seen := map[uint]bool{}
+	var after *PostCursor
+	for {
+		page, err := store.GetLatestPostsAfter(3, after)
+		if err != nil {
+			t.Fatalf("GetLatestPostsAfter() = %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, post := range page {
+			if seen[post.ID] {
+				t.Fatalf("post %v was returned twice", post.ID)
+			}
+			seen[post.ID] = true
+		}
+		last := page[len(page)-1]
+		after = &PostCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	if len(seen) != len(created) {
+		t.Errorf("paged %v posts, want %v", len(seen), len(created))
+	}
+}
+
+func TestMemoryStoreUniquenessConflicts(t *testing.T) {
+	store := NewMemoryStore()
+	ids := createPosts(t, store, 1)
+	postID := ids[0]
+
+	repost := func() error {
+		return store.CreatePost(&models.Post{AuthorID: 2, RepostOfID: &postID}, nil)
+	}
+
+	tests := []struct {
+		name   string
+		first  func() error
+		second func() error
+		want   error
+	}{
+		{
+			name:   "like",
+			first:  func() error { return store.LikePost(2, postID) },
+			second: func() error { return store.LikePost(2, postID) },
+			want:   ErrAlreadyLiked,
+		},
+		{
+			name:   "unlike",
+			first:  func() error { return store.UnlikePost(2, postID) },
+			second: func() error { return store.UnlikePost(2, postID) },
+			want:   ErrNotLiked,
+		},
+		{
+			name:   "bookmark",
+			first:  func() error { return store.BookmarkPost(2, postID) },
+			second: func() error { return store.BookmarkPost(2, postID) },
+			want:   ErrAlreadyBookmarked,
+		},
+		{
+			name:   "unbookmark",
+			first:  func() error { return store.UnbookmarkPost(2, postID) },
+			second: func() error { return store.UnbookmarkPost(2, postID) },
+			want:   ErrNotBookmarked,
+		},
+		{
+			name:   "repost",
+			first:  repost,
+			second: repost,
+			want:   ErrAlreadyReposted,
+		},
+		{
+			name:   "undo repost",
+			first:  func() error { return store.DeleteRepost(2, postID) },
+			second: func() error { return store.DeleteRepost(2, postID) },
+			want:   ErrNotReposted,
+		},
+	}
+	for _, tt := range tests {
+		if err := tt.first(); err != nil {
+			t.Fatalf("%v: first call = %v", tt.name, err)
+		}
+		err := tt.second()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%v: second call = %v, want %v", tt.name, err, tt.want)
+		}
+		if !errors.Is(err, ErrConflict) {
+			t.Errorf("%v: %v is not a conflict", tt.name, err)
+		}
+	}
+
+	post, _ := store.GetPost(postID)
+	if post.LikesCount != 0 || post.RepostsCount != 0 {
+		t.Errorf("counters = %v likes, %v reposts after undoing, want 0", post.LikesCount, post.RepostsCount)
+	}
+}
+
+func TestMemoryStoreSoftDelete(t *testing.T) {
+	store := NewMemoryStore()
+	ids := createPosts(t, store, 1, 1)
+	deleted, kept := ids[0], ids[1]
+
+	if err := store.DeletePost(deleted); err != nil {
+		t.Fatalf("DeletePost() = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  func() error
+		want error
+	}{
+		{name: "get", err: func() error { _, err := store.GetPost(deleted); return err }, want: ErrNotFound},
+		{name: "delete again", err: func() error { return store.DeletePost(deleted) }, want: ErrNotFound},
+		{name: "like", err: func() error { return store.LikePost(2, deleted) }, want: ErrNotFound},
+		{name: "comment", err: func() error { return store.CreateComment(&models.Comment{PostID: deleted}) }, want: ErrNotFound},
+		{name: "get deleted", err: func() error { _, err := store.GetDeletedPost(deleted); return err }},
+		{name: "get deleted of a live post", err: func() error { _, err := store.GetDeletedPost(kept); return err }, want: ErrNotFound},
+	}
+	for _, tt := range tests {
+		if err := tt.err(); !errors.Is(err, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	latest, _ := store.GetLatestPosts(10, 0)
+	if got := postIDs(latest); !equalIDs(got, []uint{kept}) {
+		t.Errorf("GetLatestPosts() = %v, want %v", got, []uint{kept})
+	}
+	if count, _ := store.CountPosts(1); count != 1 {
+		t.Errorf("CountPosts() = %v, want 1", count)
+	}
+	trash, _ := store.GetDeletedPosts(10, 0, 1)
+	if got := postIDs(trash); !equalIDs(got, []uint{deleted}) {
+		t.Errorf("GetDeletedPosts() = %v, want %v", got, []uint{deleted})
+	}
+
+	if _, err := store.RestorePost(deleted); err != nil {
+		t.Fatalf("RestorePost() = %v", err)
+	}
+	if _, err := store.GetPost(deleted); err != nil {
+		t.Errorf("GetPost() after restoring = %v", err)
+	}
+	if _, err := store.RestorePost(deleted); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RestorePost() of a live post = %v, want %v", err, ErrNotFound)
+	}
+
+	store.DeletePost(deleted)
+	purged, err := store.PurgeDeletedPosts(time.Now().Add(time.Minute), 10)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDeletedPosts() = %v, %v, want 1", purged, err)
+	}
+	if _, err := store.GetDeletedPost(deleted); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetDeletedPost() after purging = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestMemoryStoreCommentDepth(t *testing.T) {
+	store := NewMemoryStore()
+	ids := createPosts(t, store, 1, 1)
+	postID, otherPostID := ids[0], ids[1]
+
+	root := models.Comment{PostID: postID, Body: "root"}
+	if err := store.CreateComment(&root); err != nil {
+		t.Fatalf("CreateComment() = %v", err)
+	}
+
+	// build the deepest allowed chain of replies below the root
+	parent := root
+	for depth := uint(1); depth <= MaxCommentDepth; depth++ {
+		parentID := parent.ID
+		reply := models.Comment{PostID: postID, ParentID: &parentID, Body: "reply"}
+		if err := store.CreateComment(&reply); err != nil {
+			t.Fatalf("CreateComment() at depth %v = %v", depth, err)
+		}
+		if reply.Depth != depth || reply.RootID == nil || *reply.RootID != root.ID {
+			t.Fatalf("reply at depth %v has depth %v and root %v", depth, reply.Depth, reply.RootID)
+		}
+		parent = reply
+	}
+
+	missingID := uint(1000)
+	tests := []struct {
+		name     string
+		postID   uint
+		parentID uint
+		want     error
+	}{
+		{name: "reply to the root", postID: postID, parentID: root.ID},
+		{name: "reply below the max depth", postID: postID, parentID: parent.ID, want: ErrCommentTooDeep},
+		{name: "parent on another post", postID: otherPostID, parentID: root.ID, want: ErrParentMismatch},
+		{name: "missing parent", postID: postID, parentID: missingID, want: ErrNotFound},
+	}
+	for _, tt := range tests {
+		parentID := tt.parentID
+		err := store.CreateComment(&models.Comment{PostID: tt.postID, ParentID: &parentID})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	post, _ := store.GetPost(postID)
+	if want := uint(MaxCommentDepth + 2); post.CommentsCount != want {
+		t.Errorf("CommentsCount = %v, want %v", post.CommentsCount, want)
+	}
+
+	// deleting a reply removes the replies below it too
+	if err := store.DeleteComment(*parent.ParentID); err != nil {
+		t.Fatalf("DeleteComment() = %v", err)
+	}
+	if _, err := store.GetComment(parent.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetComment() of a removed reply = %v, want %v", err, ErrNotFound)
+	}
+	post, _ = store.GetPost(postID)
+	if want := uint(MaxCommentDepth); post.CommentsCount != want {
+		t.Errorf("CommentsCount after deleting = %v, want %v", post.CommentsCount, want)
+	}
+}
+
+func TestMemoryStoreDeleteRepostRemovesItsRows(t *testing.T) {
+	store := NewMemoryStore()
+	ids := createPosts(t, store, 1)
+	originalID := ids[0]
+
+	repost := models.Post{AuthorID: 2, RepostOfID: &originalID}
+	if err := store.CreatePost(&repost, nil); err != nil {
+		t.Fatalf("CreatePost() = %v", err)
+	}
+	if err := store.LikePost(3, repost.ID); err != nil {
+		t.Fatalf("LikePost() = %v", err)
+	}
+	if err := store.BookmarkPost(3, repost.ID); err != nil {
+		t.Fatalf("BookmarkPost() = %v", err)
+	}
+	if err := store.CreateComment(&models.Comment{PostID: repost.ID, AuthorID: 3}); err != nil {
+		t.Fatalf("CreateComment() = %v", err)
+	}
+	err := store.CreateNotifications([]models.Notification{{UserID: 2, ActorID: 3, Type: models.NotificationLike, PostID: &repost.ID}})
+	if err != nil {
+		t.Fatalf("CreateNotifications() = %v", err)
+	}
+
+	if err := store.DeleteRepost(2, originalID); err != nil {
+		t.Fatalf("DeleteRepost() = %v", err)
+	}
+
+	if len(store.likes) != 0 || len(store.bookmarks) != 0 || len(store.comments) != 0 || len(store.notifications) != 0 {
+		t.Errorf("rows left after deleting the repost: %v likes, %v bookmarks, %v comments, %v notifications",
+			len(store.likes), len(store.bookmarks), len(store.comments), len(store.notifications))
+	}
+	if _, err := store.GetDeletedPost(repost.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetDeletedPost() of the repost = %v, want %v", err, ErrNotFound)
+	}
+}
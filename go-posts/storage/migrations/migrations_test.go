@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+// TestRunnerMatchesGoUsers keeps this file identical to its go-users copy. It
+// is skipped when the go-users sources are not next to go-posts.
+func TestRunnerMatchesGoUsers(t *testing.T) {
+	other, err := os.ReadFile("../../../go-users/storage/migrations/migrations.go")
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skip("go-users sources not found")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	own, err := os.ReadFile("migrations.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(own, other) {
+		t.Error("migrations.go differs from its go-users copy, apply the change to both")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	for i, migration := range migrations {
+		if migration.Up == "" || migration.Down == "" {
+			t.Errorf("migration %d_%s misses its up or down file", migration.Version, migration.Name)
+		}
+		if i > 0 && migration.Version <= migrations[i-1].Version {
+			t.Errorf("migration %d_%s is out of order", migration.Version, migration.Name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+-- The schema previously created by AutoMigrate. Every statement is guarded,
+-- so databases created that way are adopted as they are.
+
+CREATE TABLE IF NOT EXISTS posts (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    title text,
+    body text,
+    author_id bigint,
+    likes_count bigint,
+    comments_count bigint,
+    edited_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_likes_count ON posts (likes_count DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_author_id ON posts (author_id, created_at DESC, id DESC);
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(body, '')), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector);
+
+CREATE TABLE IF NOT EXISTS likes (
+    user_id bigint NOT NULL,
+    post_id bigint NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (user_id, post_id)
+);
+CREATE INDEX IF NOT EXISTS idx_likes_post_id ON likes (post_id);
+CREATE INDEX IF NOT EXISTS idx_likes_user_id_created_at ON likes (user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    post_id bigint,
+    author_id bigint,
+    parent_id bigint,
+    root_id bigint,
+    depth bigint,
+    body text
+);
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments (post_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_root_id ON comments (root_id);
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    post_id bigint,
+    title text,
+    body text
+);
+CREATE INDEX IF NOT EXISTS idx_post_revisions_deleted_at ON post_revisions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_post_revisions_post_id ON post_revisions (post_id);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id bigserial PRIMARY KEY,
+    name text,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags (name);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+    post_id bigint NOT NULL,
+    tag_id bigint NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (post_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag_id ON post_tags (tag_id);
+CREATE INDEX IF NOT EXISTS idx_post_tags_created_at ON post_tags (created_at);
@@ -0,0 +1,2 @@
+-- Serves the trash bin of an author.
+CREATE INDEX IF NOT EXISTS idx_posts_trash ON posts (author_id, deleted_at DESC) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'published';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS publish_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_posts_drafts ON posts (author_id, updated_at DESC) WHERE status <> 'published';
+CREATE INDEX IF NOT EXISTS idx_posts_scheduled ON posts (publish_at) WHERE status = 'scheduled';
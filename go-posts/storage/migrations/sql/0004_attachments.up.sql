@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS attachments (
+    id bigserial PRIMARY KEY,
+    post_id bigint,
+    uploader_id bigint NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL,
+    width bigint NOT NULL DEFAULT 0,
+    height bigint NOT NULL DEFAULT 0,
+    blob_key text NOT NULL,
+    thumbnail_key text NOT NULL DEFAULT '',
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_post_id ON attachments (post_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_unattached ON attachments (created_at) WHERE post_id IS NULL;
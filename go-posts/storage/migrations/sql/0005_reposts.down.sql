@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_posts_author_repost;
+DROP INDEX IF EXISTS idx_posts_quote_of_id;
+DROP INDEX IF EXISTS idx_posts_repost_of_id;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS reposts_count;
+ALTER TABLE posts DROP COLUMN IF EXISTS quote_of_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS repost_of_id;
@@ -0,0 +1,10 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS repost_of_id bigint;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS quote_of_id bigint;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS reposts_count bigint NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_posts_repost_of_id ON posts (repost_of_id);
+CREATE INDEX IF NOT EXISTS idx_posts_quote_of_id ON posts (quote_of_id);
+
+-- a user can repost a post only once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_author_repost ON posts (author_id, repost_of_id)
+    WHERE repost_of_id IS NOT NULL AND deleted_at IS NULL;
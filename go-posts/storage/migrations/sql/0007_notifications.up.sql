@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    actor_id bigint NOT NULL,
+    type text NOT NULL,
+    post_id bigint,
+    comment_id bigint,
+    read_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id_created_at ON notifications (user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_notifications_post_id ON notifications (post_id);
+
+-- likes and follows are notified once per pair, however often they are redone
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_like ON notifications (user_id, actor_id, post_id)
+    WHERE type = 'like';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_follow ON notifications (user_id, actor_id)
+    WHERE type = 'follow';
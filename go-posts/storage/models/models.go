@@ -1,11 +1,136 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
 
+	"gorm.io/gorm"
+)
+
+// Statuses of a post. Only published posts appear in feeds, drafts and
+// scheduled posts are visible to their author only.
+const (
+	PostDraft     = "draft"
+	PostScheduled = "scheduled"
+	PostPublished = "published"
+)
+
+// Post is a post of a user. CreatedAt of a draft or a scheduled post is reset
+// when it gets published, so it enters the feeds at the top.
+//
+// A repost shares another post as it is: it has no title nor body and
+// RepostOfID points at the original. A quote is a regular post commenting on
+// the original pointed at by QuoteOfID. RepostsCount counts the live published
+// reposts and quotes of a post.
 type Post struct {
 	gorm.Model
-	Title      string
-	Body       string
-	AuthorID   uint
-	LikesCount uint
+	Title         string
+	Body          string
+	AuthorID      uint
+	LikesCount    uint
+	CommentsCount uint
+	EditedAt      *time.Time `json:"edited_at"`
+	Status        string     `gorm:"default:published" json:"status"`
+	PublishAt     *time.Time `json:"publish_at"`
+	RepostOfID    *uint      `gorm:"index" json:"repost_of_id"`
+	QuoteOfID     *uint      `gorm:"index" json:"quote_of_id"`
+	RepostsCount  uint
+}
+
+// OriginalID returns the id of the post reposted or quoted by the post.
+func (post *Post) OriginalID() *uint {
+	if post.RepostOfID != nil {
+		return post.RepostOfID
+	}
+	return post.QuoteOfID
+}
+
+// Like is a single user's like of a post. The composite primary key
+// guarantees that a user can like a given post only once.
+type Like struct {
+	UserID    uint `gorm:"primaryKey;autoIncrement:false"`
+	PostID    uint `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time
+}
+
+// Bookmark is a post saved by a user to their private list. Like with likes,
+// the composite primary key lets a user save a given post only once.
+type Bookmark struct {
+	UserID    uint `gorm:"primaryKey;autoIncrement:false"`
+	PostID    uint `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time
+}
+
+// Comment is a comment on a post. Replies point to the comment they answer
+// through ParentID and to the top level comment of their thread through
+// RootID, so a whole thread can be fetched with a single query.
+type Comment struct {
+	gorm.Model
+	PostID   uint `gorm:"index"`
+	AuthorID uint
+	ParentID *uint `gorm:"index"`
+	RootID   *uint `gorm:"index"`
+	Depth    uint
+	Body     string
+}
+
+// PostRevision is a snapshot of a post's title and body taken right before
+// the post was edited.
+type PostRevision struct {
+	gorm.Model
+	PostID uint `gorm:"index"`
+	Title  string
+	Body   string
+}
+
+type Tag struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"uniqueIndex"`
+	CreatedAt time.Time
+}
+
+// PostTag links a post to a tag it mentions. CreatedAt is the moment the tag
+// was attached, which is what trending tags are computed over.
+type PostTag struct {
+	PostID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	TagID     uint      `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// Attachment is an uploaded file. It belongs to its uploader only until it is
+// attached to one of their posts. Images also have a thumbnail and their
+// dimensions set.
+type Attachment struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	PostID       *uint     `gorm:"index" json:"post_id"`
+	UploaderID   uint      `json:"uploader_id"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width,omitempty"`
+	Height       int       `json:"height,omitempty"`
+	BlobKey      string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Kinds of notifications.
+const (
+	NotificationMention = "mention"
+	NotificationLike    = "like"
+	NotificationComment = "comment"
+	NotificationFollow  = "follow"
+)
+
+// Notification tells UserID that ActorID interacted with them. PostID and
+// CommentID point at the subject of the interaction, when it has one. A user
+// is notified of a given like or follow only once, even if it is undone and
+// repeated.
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `json:"user_id"`
+	ActorID   uint       `json:"actor_id"`
+	Type      string     `json:"type"`
+	PostID    *uint      `gorm:"index" json:"post_id"`
+	CommentID *uint      `json:"comment_id"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
 }
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"go-posts/storage/models"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// CreateNotifications saves the notifications. Repeated like and follow
+// notifications are skipped silently.
+func (store *PostgreStore) CreateNotifications(notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	err := store.Conn.Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications).Error
+	return translateError(err)
+}
+
+// GetNotifications returns the user's notifications, newest first.
+func (store *PostgreStore) GetNotifications(limit int, offset int, userID uint) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := store.Conn.Where("user_id = ?", userID).
+		Order("created_at desc, id desc").
+		Limit(limit).Offset(offset).
+		Find(&notifications).Error
+	return notifications, translateError(err)
+}
+
+func (store *PostgreStore) CountUnreadNotifications(userID uint) (int64, error) {
+	var count int64
+	err := store.Conn.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, translateError(err)
+}
+
+// MarkNotificationsRead marks the given notifications of the user as read, or
+// all of them when no ids are given, and returns how many were unread.
+func (store *PostgreStore) MarkNotificationsRead(userID uint, notificationIDs []uint) (int64, error) {
+	query := store.Conn.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if len(notificationIDs) > 0 {
+		query = query.Where("id IN ?", notificationIDs)
+	}
+
+	res := query.Update("read_at", time.Now())
+	return res.RowsAffected, translateError(res.Error)
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"errors"
+	"go-posts/storage/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAlreadyReposted error = &kindError{msg: "post is already reposted", kind: ErrConflict}
+	ErrNotReposted     error = &kindError{msg: "post is not reposted", kind: ErrConflict}
+)
+
+// resolveOriginal points a new repost or quote at its original, which has to
+// be a live published post. Reposting or quoting a repost refers to the post
+// it shares instead. The caller must run it in the transaction creating the
+// post.
+func resolveOriginal(tx *gorm.DB, post *models.Post) error {
+	originalID := post.OriginalID()
+	if originalID == nil {
+		return nil
+	}
+
+	var original models.Post
+	if err := tx.Scopes(published).Select("id", "repost_of_id").First(&original, *originalID).Error; err != nil {
+		return err
+	}
+	if original.RepostOfID == nil {
+		return nil
+	}
+
+	shared := *original.RepostOfID
+	if err := tx.Scopes(published).Select("id").First(&original, shared).Error; err != nil {
+		return err
+	}
+	if post.RepostOfID != nil {
+		post.RepostOfID = &shared
+	} else {
+		post.QuoteOfID = &shared
+	}
+	return nil
+}
+
+// updateRepostsCount applies op ("+" or "-") to the counter of the post's
+// original, if it has one. Unpublished quotes are not counted until they get
+// published. Deleted originals are counted too, so their counter is right if
+// they get restored.
+func updateRepostsCount(tx *gorm.DB, post models.Post, op string) error {
+	originalID := post.OriginalID()
+	if originalID == nil || post.Status != models.PostPublished {
+		return nil
+	}
+
+	return tx.Unscoped().Model(&models.Post{}).Where("id = ?", *originalID).
+		UpdateColumn("reposts_count", gorm.Expr("GREATEST(reposts_count "+op+" 1, 0)")).Error
+}
+
+// DeleteRepost removes the user's repost of the post for good. The post can
+// also be given through any repost of it.
+func (store *PostgreStore) DeleteRepost(userID uint, postID uint) error {
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		var original models.Post
+		if err := tx.Unscoped().Select("id", "repost_of_id").First(&original, postID).Error; err != nil {
+			return err
+		}
+		if original.RepostOfID != nil {
+			postID = *original.RepostOfID
+		}
+
+		var repost models.Post
+		err := tx.Where("author_id = ? AND repost_of_id = ?", userID, postID).First(&repost).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotReposted
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := deletePosts(tx, []uint{repost.ID}); err != nil {
+			return err
+		}
+		return updateRepostsCount(tx, repost, "-")
+	})
+	return translateError(err)
+}
+
+// GetRepostedPostIDs reports which of the given posts are reposted by the
+// user.
+func (store *PostgreStore) GetRepostedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error) {
+	reposted := make(map[uint]bool)
+	if len(postIDs) == 0 {
+		return reposted, nil
+	}
+
+	var ids []uint
+	err := store.Conn.Model(&models.Post{}).
+		Where("author_id = ? AND repost_of_id IN ?", userID, postIDs).
+		Pluck("repost_of_id", &ids).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	for _, id := range ids {
+		reposted[id] = true
+	}
+	return reposted, nil
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"go-posts/storage/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpdatePost replaces the post's title and body, keeping the previous version
+// as a PostRevision, and returns the updated post.
+func (store *PostgreStore) UpdatePost(postID uint, title string, body string) (models.Post, error) {
+	var post models.Post
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&post, postID).Error; err != nil {
+			return err
+		}
+
+		revision := &models.PostRevision{PostID: post.ID, Title: post.Title, Body: post.Body}
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		post.Title = title
+		post.Body = body
+		post.EditedAt = &now
+
+		return tx.Model(&post).Updates(map[string]interface{}{
+			"title":     title,
+			"body":      body,
+			"edited_at": now,
+		}).Error
+	})
+	return post, translateError(err)
+}
+
+// GetPostRevisions returns the previous versions of the post, newest first.
+func (store *PostgreStore) GetPostRevisions(postID uint) ([]models.PostRevision, error) {
+	var revisions []models.PostRevision
+	err := store.Conn.Where("post_id = ?", postID).Order("created_at desc, id desc").Find(&revisions).Error
+	return revisions, translateError(err)
+}
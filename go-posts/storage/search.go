@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"go-posts/storage/models"
+	"html"
+	"strings"
+	"time"
+)
+
+// SearchQuery describes a full-text search over posts. Zero valued filters
+// are not applied. To is exclusive.
+type SearchQuery struct {
+	Text     string
+	AuthorID uint
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	After    *PostCursor
+}
+
+// SearchResult is a post matching a search together with its rank and
+// snippets of the title and body with the matched words highlighted. The
+// snippets are HTML: the text is escaped and the matches are wrapped in <mark>.
+type SearchResult struct {
+	models.Post
+	Rank         float32 `json:"rank"`
+	TitleSnippet string  `json:"title_snippet"`
+	BodySnippet  string  `json:"body_snippet"`
+}
+
+// ts_headline does not escape the text around the matches, so it delimits them
+// with control characters, which are stripped from the text beforehand, and
+// markSnippet turns them into tags once the rest is escaped.
+const (
+	snippetStart    = "\x02"
+	snippetStop     = "\x03"
+	headlineOptions = "StartSel=" + snippetStart + ", StopSel=" + snippetStop + ", MaxWords=35, MinWords=15, MaxFragments=2"
+)
+
+var snippetReplacer = strings.NewReplacer(snippetStart, "<mark>", snippetStop, "</mark>")
+
+// markSnippet escapes a snippet delimited by snippetStart and snippetStop and
+// wraps its matches in <mark>.
+func markSnippet(snippet string) string {
+	return snippetReplacer.Replace(html.EscapeString(snippet))
+}
+
+// stripSnippetDelimiters removes the delimiters from user text.
+func stripSnippetDelimiters(text string) string {
+	return strings.NewReplacer(snippetStart, "", snippetStop, "").Replace(text)
+}
+
+// SearchPosts returns posts matching the query ordered by (rank, id)
+// descending. The query text uses the web search syntax (quoted phrases, "or",
+// "-" for negation).
+func (store *PostgreStore) SearchPosts(query SearchQuery) ([]SearchResult, error) {
+	var results []SearchResult
+
+	db := store.Conn.Unscoped().
+		Table("posts, websearch_to_tsquery('english', ?) AS query", query.Text).
+		Select(
+			"posts.*, ts_rank(posts.search_vector, query) AS rank, "+
+				"ts_headline('english', translate(posts.title, ?, ''), query, ?) AS title_snippet, "+
+				"ts_headline('english', translate(posts.body, ?, ''), query, ?) AS body_snippet",
+			snippetStart+snippetStop, headlineOptions, snippetStart+snippetStop, headlineOptions,
+		).
+		Where("posts.deleted_at IS NULL AND posts.search_vector @@ query").
+		Scopes(published)
+
+	if query.AuthorID != 0 {
+		db = db.Where("posts.author_id = ?", query.AuthorID)
+	}
+	if query.From != nil {
+		db = db.Where("posts.created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("posts.created_at < ?", *query.To)
+	}
+	if query.After != nil {
+		db = db.Where("(ts_rank(posts.search_vector, query), posts.id) < (?::real, ?)", query.After.Rank, query.After.ID)
+	}
+
+	err := db.Order("rank desc, posts.id desc").Limit(query.Limit).Scan(&results).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	for i := range results {
+		results[i].TitleSnippet = markSnippet(results[i].TitleSnippet)
+		results[i].BodySnippet = markSnippet(results[i].BodySnippet)
+	}
+	return results, nil
+}
@@ -1,7 +1,7 @@
 package storage
 
 import (
-	"errors"
+	"go-posts/storage/migrations"
 	"go-posts/storage/models"
 	"os"
 	"time"
@@ -12,28 +12,97 @@ import (
 	"gorm.io/gorm"
 )
 
+// Storage persists posts and everything attached to them. Missing records are
+// reported with ErrNotFound and uniqueness violations with ErrConflict.
 type Storage interface {
 	CreateStorage()
 	Migrate()
-	CreatePost(post *models.Post) error
-	GetLatestPosts(limit int, offset int) []models.Post
-	GetUsersPosts(limit int, offset int, authorID uint) []models.Post
-	GetMostLikedPosts(limit int, offset int) []models.Post
-	GetPost(postID uint) models.Post
-	CountPosts(userID uint) int64
+	// CreatePost saves the post and attaches the given attachments of its
+	// author to it, all or nothing. Reposts and quotes get their original
+	// resolved and its counter incremented.
+	CreatePost(post *models.Post, attachmentIDs []uint) error
+	GetLatestPosts(limit int, offset int) ([]models.Post, error)
+	GetUsersPosts(limit int, offset int, authorID uint) ([]models.Post, error)
+	GetMostLikedPosts(limit int, offset int) ([]models.Post, error)
+	GetLatestPostsAfter(limit int, after *PostCursor) ([]models.Post, error)
+	GetMostLikedPostsAfter(limit int, after *PostCursor) ([]models.Post, error)
+	GetUsersPostsAfter(limit int, after *PostCursor, authorID uint) ([]models.Post, error)
+	GetPostsByAuthorsAfter(limit int, after *PostCursor, authorIDs []uint) ([]models.Post, error)
+	SearchPosts(query SearchQuery) ([]SearchResult, error)
+	SetPostTags(postID uint, tags []string) error
+	GetPostsByTag(limit int, offset int, tag string) ([]models.Post, error)
+	GetTrendingTags(limit int, since time.Time) ([]TagCount, error)
+	GetPost(postID uint) (models.Post, error)
+	GetPostsByIDs(postIDs []uint) ([]models.Post, error)
+	CountPosts(userID uint) (int64, error)
 	DeletePost(postID uint) error
-	LikePost(postID uint) error
-	UnlikePost(postID uint) error
+	UpdatePost(postID uint, title string, body string) (models.Post, error)
+	GetPostRevisions(postID uint) ([]models.PostRevision, error)
+	GetDeletedPosts(limit int, offset int, authorID uint) ([]models.Post, error)
+	GetDeletedPost(postID uint) (models.Post, error)
+	RestorePost(postID uint) (models.Post, error)
+	PurgeDeletedPosts(deletedBefore time.Time, limit int) (int64, error)
+	GetDrafts(limit int, offset int, authorID uint) ([]models.Post, error)
+	UpdateDraft(postID uint, title string, body string, status string, publishAt *time.Time) (models.Post, error)
+	PublishScheduledPosts(now time.Time, limit int) ([]models.Post, error)
+	CreateAttachment(attachment *models.Attachment) error
+	GetAttachment(attachmentID uint) (models.Attachment, error)
+	GetAttachments(postIDs []uint) (map[uint][]models.Attachment, error)
+	GetOrphanedAttachments(uploadedBefore time.Time, limit int) ([]models.Attachment, error)
+	DeleteAttachments(attachmentIDs []uint) error
+	DeleteRepost(userID uint, postID uint) error
+	GetRepostedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error)
+	LikePost(userID uint, postID uint) error
+	UnlikePost(userID uint, postID uint) error
+	GetLikedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error)
+	GetLikedPosts(limit int, offset int, userID uint) ([]models.Post, error)
+	BookmarkPost(userID uint, postID uint) error
+	UnbookmarkPost(userID uint, postID uint) error
+	GetBookmarkedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error)
+	GetBookmarkedPosts(limit int, offset int, userID uint) ([]models.Post, error)
+	CreateNotifications(notifications []models.Notification) error
+	GetNotifications(limit int, offset int, userID uint) ([]models.Notification, error)
+	CountUnreadNotifications(userID uint) (int64, error)
+	MarkNotificationsRead(userID uint, notificationIDs []uint) (int64, error)
+	CreateComment(comment *models.Comment) error
+	GetComment(commentID uint) (models.Comment, error)
+	GetCommentThreads(limit int, offset int, postID uint) ([]models.Comment, error)
+	UpdateComment(commentID uint, body string) error
+	DeleteComment(commentID uint) error
+}
+
+// New creates the storage selected by the STORAGE_BACKEND ENV: "postgres"
+// (the default, connecting to DB_ADDR) or "memory" for an in-process store,
+// which needs no database but loses its data on restart.
+func New() Storage {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "postgres":
+		store := &PostgreStore{}
+		store.CreateStorage()
+		store.Migrate()
+		return store
+	case "memory":
+		log.Info("Using in-memory storage")
+		return NewMemoryStore()
+	default:
+		log.Fatal("Unknown STORAGE_BACKEND", "backend", backend)
+		return nil
+	}
+}
+
+// published restricts a posts query to published posts.
+func published(db *gorm.DB) *gorm.DB {
+	return db.Where("posts.status = ?", models.PostPublished)
 }
 
 type PostgreStore struct {
 	Conn *gorm.DB
 }
 
-func (store *PostgreStore) CountPosts(userID uint) int64 {
+func (store *PostgreStore) CountPosts(userID uint) (int64, error) {
 	var count int64
-	store.Conn.Model(models.Post{}).Where("author_id = ?", userID).Count(&count)
-	return count
+	err := store.Conn.Model(models.Post{}).Scopes(published).Where("author_id = ?", userID).Count(&count).Error
+	return count, translateError(err)
 }
 
 // CreateStorage creates *gorm.DB instance with connection to the database
@@ -45,7 +114,7 @@ func (store *PostgreStore) CreateStorage() {
 	log.Debug("--------DB_ADDR:------", dsn)
 
 	for i := 0; i < 3; i++ {
-		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 		if err == nil {
 			store.Conn = db
 			return
@@ -56,60 +125,105 @@ func (store *PostgreStore) CreateStorage() {
 	log.Fatal("Unable to reach database")
 }
 
-// Migrate automatically migrates models to the database (Post model in this case)
-func (store *PostgreStore) Migrate() {
-	log.Debug("AutoMigrating models...")
-	store.Conn.AutoMigrate(&models.Post{})
+// migrationsLockID is the advisory lock of the go-posts migrations, distinct
+// from the go-users one.
+const migrationsLockID = 7_380_235_001
+
+// Migrator returns the migrator of the connected database
+func (store *PostgreStore) Migrator() (*migrations.Migrator, error) {
+	return migrations.New(store.Conn, migrationsLockID)
 }
 
-func (store *PostgreStore) CreatePost(post *models.Post) error {
-	pk := store.Conn.Create(post)
-	if pk == nil {
-		return errors.New("Failed to create post")
+// Migrate applies the pending schema migrations
+func (store *PostgreStore) Migrate() {
+	log.Debug("Applying migrations...")
+
+	migrator, err := store.Migrator()
+	if err != nil {
+		log.Fatal("Unable to load migrations", "err", err)
 	}
 
-	return nil
+	applied, err := migrator.Up()
+	for _, migration := range applied {
+		log.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+	}
+	if err != nil {
+		log.Fatal("Unable to migrate database", "err", err)
+	}
 }
 
-func (store *PostgreStore) GetLatestPosts(limit int, offset int) []models.Post {
-	var posts []models.Post
-	store.Conn.Limit(limit).Offset(offset).Find(&posts).Order("id desc")
-	return posts
+func (store *PostgreStore) CreatePost(post *models.Post, attachmentIDs []uint) error {
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		if err := resolveOriginal(tx, post); err != nil {
+			return err
+		}
+		if post.RepostOfID != nil {
+			var reposts int64
+			err := tx.Model(&models.Post{}).Where("author_id = ? AND repost_of_id = ?", post.AuthorID, *post.RepostOfID).Count(&reposts).Error
+			if err != nil {
+				return err
+			}
+			if reposts > 0 {
+				return ErrAlreadyReposted
+			}
+		}
+
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		if err := attach(tx, post, attachmentIDs); err != nil {
+			return err
+		}
+		return updateRepostsCount(tx, *post, "+")
+	})
+	return translateError(err)
 }
 
-func (store *PostgreStore) GetMostLikedPosts(limit int, offset int) []models.Post {
+func (store *PostgreStore) GetLatestPosts(limit int, offset int) ([]models.Post, error) {
 	var posts []models.Post
-	store.Conn.Limit(limit).Offset(offset).Find(&posts).Order("likes_count desc")
-	return posts
+	err := store.Conn.Scopes(published).Order("created_at desc, id desc").Limit(limit).Offset(offset).Find(&posts).Error
+	return posts, translateError(err)
 }
 
-func (store *PostgreStore) GetUsersPosts(limit int, offset int, authorID uint) []models.Post {
+func (store *PostgreStore) GetMostLikedPosts(limit int, offset int) ([]models.Post, error) {
 	var posts []models.Post
-	store.Conn.Limit(limit).Offset(offset).Find(&posts).Where("author_id = ?", authorID)
-	return posts
+	err := store.Conn.Scopes(published).Order("likes_count desc, id desc").Limit(limit).Offset(offset).Find(&posts).Error
+	return posts, translateError(err)
 }
 
-func (store *PostgreStore) GetPost(postID uint) models.Post {
-	var post models.Post
-	store.Conn.First(&post, postID)
-	return post
+func (store *PostgreStore) GetUsersPosts(limit int, offset int, authorID uint) ([]models.Post, error) {
+	var posts []models.Post
+	err := store.Conn.Scopes(published).Where("author_id = ?", authorID).Order("created_at desc, id desc").Limit(limit).Offset(offset).Find(&posts).Error
+	return posts, translateError(err)
 }
 
-func (store *PostgreStore) DeletePost(postID uint) error {
-	store.Conn.Delete(&models.Post{}, postID)
-	return nil
+func (store *PostgreStore) GetPost(postID uint) (models.Post, error) {
+	var post models.Post
+	err := store.Conn.First(&post, postID).Error
+	return post, translateError(err)
 }
 
-func (store *PostgreStore) LikePost(postID uint) error {
-	post := store.GetPost(postID)
-	post.LikesCount++
-	store.Conn.Save(&post)
-	return nil
+// GetPostsByIDs returns the published posts among the given ids, in no
+// particular order.
+func (store *PostgreStore) GetPostsByIDs(postIDs []uint) ([]models.Post, error) {
+	var posts []models.Post
+	if len(postIDs) == 0 {
+		return posts, nil
+	}
+	err := store.Conn.Scopes(published).Where("id IN ?", postIDs).Find(&posts).Error
+	return posts, translateError(err)
 }
 
-func (store *PostgreStore) UnlikePost(postID uint) error {
-	post := store.GetPost(postID)
-	post.LikesCount--
-	store.Conn.Save(&post)
-	return nil
+func (store *PostgreStore) DeletePost(postID uint) error {
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		var post models.Post
+		if err := tx.First(&post, postID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&post).Error; err != nil {
+			return err
+		}
+		return updateRepostsCount(tx, post, "-")
+	})
+	return translateError(err)
 }
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"go-posts/storage/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TagCount is a tag with the number of posts it was attached to.
+type TagCount struct {
+	Name  string `json:"name"`
+	Posts int64  `json:"posts"`
+}
+
+// SetPostTags replaces the tags of the post with the given ones, creating the
+// tags that do not exist yet. Tags the post already had keep their
+// attachment time.
+func (store *PostgreStore) SetPostTags(postID uint, tags []string) error {
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		var tagIDs []uint
+		if len(tags) > 0 {
+			newTags := make([]models.Tag, len(tags))
+			for i, name := range tags {
+				newTags[i] = models.Tag{Name: name}
+			}
+			err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&newTags).Error
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Model(&models.Tag{}).Where("name IN ?", tags).Pluck("id", &tagIDs).Error; err != nil {
+				return err
+			}
+		}
+
+		stale := tx.Where("post_id = ?", postID)
+		if len(tagIDs) > 0 {
+			stale = stale.Where("tag_id NOT IN ?", tagIDs)
+		}
+		if err := stale.Delete(&models.PostTag{}).Error; err != nil {
+			return err
+		}
+
+		if len(tagIDs) == 0 {
+			return nil
+		}
+
+		links := make([]models.PostTag, len(tagIDs))
+		for i, tagID := range tagIDs {
+			links[i] = models.PostTag{PostID: postID, TagID: tagID}
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error
+	})
+	return translateError(err)
+}
+
+// GetPostsByTag returns the posts tagged with the tag, newest first.
+func (store *PostgreStore) GetPostsByTag(limit int, offset int, tag string) ([]models.Post, error) {
+	var posts []models.Post
+	err := store.Conn.
+		Joins("JOIN post_tags ON post_tags.post_id = posts.id").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Where("tags.name = ?", tag).
+		Scopes(published).
+		Order("posts.created_at desc, posts.id desc").
+		Limit(limit).Offset(offset).
+		Find(&posts).Error
+	return posts, translateError(err)
+}
+
+// GetTrendingTags returns the tags attached to the most posts since the given
+// moment.
+func (store *PostgreStore) GetTrendingTags(limit int, since time.Time) ([]TagCount, error) {
+	var counts []TagCount
+	err := store.Conn.Model(&models.PostTag{}).
+		Select("tags.name AS name, COUNT(*) AS posts").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Joins("JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL AND posts.status = ?", models.PostPublished).
+		Where("post_tags.created_at >= ?", since).
+		Group("tags.name").
+		Order("posts desc, name asc").
+		Limit(limit).
+		Scan(&counts).Error
+	return counts, translateError(err)
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"go-posts/storage/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetDeletedPosts returns the author's deleted posts, most recently deleted
+// first.
+func (store *PostgreStore) GetDeletedPosts(limit int, offset int, authorID uint) ([]models.Post, error) {
+	var posts []models.Post
+	err := store.Conn.Unscoped().
+		Where("author_id = ? AND deleted_at IS NOT NULL", authorID).
+		Order("deleted_at desc, id desc").
+		Limit(limit).Offset(offset).
+		Find(&posts).Error
+	return posts, translateError(err)
+}
+
+// GetDeletedPost returns the post only if it is deleted.
+func (store *PostgreStore) GetDeletedPost(postID uint) (models.Post, error) {
+	var post models.Post
+	err := store.Conn.Unscoped().Where("deleted_at IS NOT NULL").First(&post, postID).Error
+	return post, translateError(err)
+}
+
+// RestorePost undeletes the post and returns it.
+func (store *PostgreStore) RestorePost(postID uint) (models.Post, error) {
+	var post models.Post
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&post, postID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&post).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return updateRepostsCount(tx, post, "+")
+	})
+	if err != nil {
+		return models.Post{}, translateError(err)
+	}
+
+	return store.GetPost(postID)
+}
+
+// PurgeDeletedPosts permanently deletes up to limit posts deleted before the
+// given moment, together with their reposts, likes, bookmarks, comments,
+// revisions, tags and notifications, and returns how many posts were purged.
+// Posts being purged by another replica are skipped.
+func (store *PostgreStore) PurgeDeletedPosts(deletedBefore time.Time, limit int) (int64, error) {
+	var purged int64
+	err := store.Conn.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		err := tx.Unscoped().Model(&models.Post{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("deleted_at < ?", deletedBefore).
+			Order("deleted_at asc").
+			Limit(limit).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		var reposts []uint
+		if err := tx.Unscoped().Model(&models.Post{}).Where("repost_of_id IN ?", ids).Pluck("id", &reposts).Error; err != nil {
+			return err
+		}
+		expired := len(ids)
+		ids = append(ids, reposts...)
+
+		if err := deletePosts(tx, ids); err != nil {
+			return err
+		}
+		purged = int64(expired)
+		return nil
+	})
+	return purged, translateError(err)
+}
+
+// deletePosts deletes the posts for good, together with every row pointing at
+// them.
+func deletePosts(tx *gorm.DB, ids []uint) error {
+	for _, model := range []interface{}{&models.Like{}, &models.Bookmark{}, &models.Comment{}, &models.PostRevision{}, &models.PostTag{}, &models.Notification{}} {
+		if err := tx.Unscoped().Where("post_id IN ?", ids).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Unscoped().Delete(&models.Post{}, ids).Error
+}
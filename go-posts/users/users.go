@@ -0,0 +1,110 @@
+// Package users is a client of the go-users service, reached through the
+// users loadbalancer.
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var client = &http.Client{Timeout: 5 * time.Second}
+
+// ErrNotFound is returned when the users service does not know the user.
+var ErrNotFound = errors.New("user not found")
+
+// FollowedUser is a user followed by someone, with the size of its audience.
+type FollowedUser struct {
+	ID             uint `json:"id"`
+	FollowersCount uint `json:"followers_count"`
+}
+
+func get(path string, query string, res interface{}) error {
+	targetUrl := fmt.Sprintf("http://%v%v?%v", os.Getenv("USERS_LOADBALANCER"), path, query)
+
+	resp, err := client.Get(targetUrl)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("users service responded with %v to %v", resp.StatusCode, path)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+
+// GetFollowerIDs returns the ids of every user following userID.
+func GetFollowerIDs(userID uint) ([]uint, error) {
+	var ids []uint
+	err := get("/users/followers/ids", fmt.Sprintf("id=%d", userID), &ids)
+	return ids, err
+}
+
+// GetFollowersCount returns how many users follow userID.
+func GetFollowersCount(userID uint) (uint, error) {
+	var counts struct {
+		FollowersCount uint `json:"followers_count"`
+	}
+	err := get("/users/follows/count", fmt.Sprintf("id=%d", userID), &counts)
+	return counts.FollowersCount, err
+}
+
+// GetFollowedUsers returns every user followed by userID.
+func GetFollowedUsers(userID uint) ([]FollowedUser, error) {
+	var users []FollowedUser
+	err := get("/users/following/ids", fmt.Sprintf("id=%d", userID), &users)
+	return users, err
+}
+
+// IsFollowing reports whether followerID follows followeeID.
+func IsFollowing(followerID uint, followeeID uint) (bool, error) {
+	followed, err := GetFollowedUsers(followerID)
+	if err != nil {
+		return false, err
+	}
+	for _, user := range followed {
+		if user.ID == followeeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Author is the public identity of a user as it is embedded into posts.
+type Author struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+}
+
+// GetAuthors resolves the given user ids in a single request. Unknown ids are
+// missing from the result.
+func GetAuthors(userIDs []uint) ([]Author, error) {
+	ids := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	var authors []Author
+	err := get("/users/batch", "ids="+strings.Join(ids, ","), &authors)
+	return authors, err
+}
+
+// GetUserByUsername resolves a username, returning ErrNotFound for unknown
+// ones.
+func GetUserByUsername(username string) (Author, error) {
+	var author Author
+	err := get("/users/getbyusername", "username="+url.QueryEscape(username), &author)
+	return author, err
+}
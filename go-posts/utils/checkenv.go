@@ -8,11 +8,11 @@ import (
 func CheckENVS() {
 	error_messages := []string{}
 
-	if os.Getenv("DB_ADDR") == "" {
+	if os.Getenv("DB_ADDR") == "" && os.Getenv("STORAGE_BACKEND") != "memory" {
 		error_messages = append(error_messages, "DB_ADDR ENV is not set")
 	}
 
-	if os.Getenv("CACHE_ADDR") == "" {
+	if os.Getenv("CACHE_ADDR") == "" && os.Getenv("CACHE_BACKEND") != "memory" {
 		error_messages = append(error_messages, "CACHE_ADDR ENV is not set")
 	}
 
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// GetEnvInt returns the integer value of the ENV or def if it is not set.
+func GetEnvInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warn("Invalid integer ENV, using default", "env", name, "value", value, "default", def)
+		return def
+	}
+	return n
+}
+
+// GetEnvBool returns the boolean value of the ENV (e.g. "true" or "1") or def
+// if it is not set.
+func GetEnvBool(name string, def bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Warn("Invalid boolean ENV, using default", "env", name, "value", value, "default", def)
+		return def
+	}
+	return b
+}
+
+// GetEnvDuration returns the duration value of the ENV (e.g. "90s") or def if
+// it is not set.
+func GetEnvDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warn("Invalid duration ENV, using default", "env", name, "value", value, "default", def)
+		return def
+	}
+	return d
+}
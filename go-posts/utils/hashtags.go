@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaxHashtagLength is the longest tag that is recognised, longer ones are
+// ignored entirely.
+const MaxHashtagLength = 50
+
+// A hashtag starts with # that is not glued to a preceding word character, so
+// that URL fragments like "page#section" are not picked up.
+var hashtagRegexp = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/])#([\p{L}\p{N}_]+)`)
+
+// ParseHashtags returns the distinct hashtags of the text, lowercased and
+// without the leading #, in order of their first appearance.
+func ParseHashtags(text string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+
+	for _, match := range hashtagRegexp.FindAllStringSubmatch(text, -1) {
+		tag := strings.ToLower(match[1])
+		if len([]rune(tag)) > MaxHashtagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
@@ -0,0 +1,25 @@
+package utils
+
+import "regexp"
+
+// Like a hashtag, a mention must not be glued to a preceding word character,
+// so that e-mail addresses are not picked up.
+var mentionRegexp = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&@/])@([\p{L}\p{N}_]+)`)
+
+// ParseMentions returns the distinct usernames mentioned in the text, without
+// the leading @, in order of their first appearance.
+func ParseMentions(text string) []string {
+	usernames := []string{}
+	seen := map[string]bool{}
+
+	for _, match := range mentionRegexp.FindAllStringSubmatch(text, -1) {
+		username := match[1]
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+
+	return usernames
+}
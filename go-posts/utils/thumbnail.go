@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+)
+
+// Thumbnail scales the image down so that its longer side is at most maxSide
+// pixels, averaging the source pixels covered by every thumbnail pixel.
+// Transparent areas are flattened onto white, so the thumbnail can be encoded
+// as JPEG.
+func Thumbnail(src image.Image, maxSide int) *image.RGBA {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	dw, dh := w, h
+	if w >= h && w > maxSide {
+		dw, dh = maxSide, max(1, h*maxSide/w)
+	} else if h > w && h > maxSide {
+		dw, dh = max(1, w*maxSide/h), maxSide
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := bounds.Min.Y+y*h/dh, bounds.Min.Y+max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := bounds.Min.X+x*w/dw, bounds.Min.X+max((x+1)*w/dw, x*w/dw+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+
+			// the channels are premultiplied by alpha, adding the missing
+			// coverage as white flattens the pixel onto a white background
+			white := 0xffff - a/n
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r/n + white) >> 8),
+				G: uint8((g/n + white) >> 8),
+				B: uint8((b/n + white) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
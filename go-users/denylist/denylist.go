@@ -0,0 +1,43 @@
+// Package denylist keeps track of the revoked access tokens of go-users until
+// they would have expired anyway.
+package denylist
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Denylist is a set of revoked token or session IDs with expiring entries.
+type Denylist interface {
+	Add(ctx context.Context, id string, ttl time.Duration) error
+	// Contains reports whether any of the ids is denied.
+	Contains(ctx context.Context, ids ...string) (bool, error)
+}
+
+// New creates the denylist selected by the DENYLIST_BACKEND ENV: "redis" (the
+// default, connecting to DENYLIST_ADDR) or "memory". The memory one is not
+// shared between replicas, a token revoked by one of them stays valid on the
+// others, so it has to be chosen explicitly and only fits a single replica.
+func New(logger *zap.Logger) Denylist {
+	switch backend := os.Getenv("DENYLIST_BACKEND"); backend {
+	case "", "redis":
+		addr := os.Getenv("DENYLIST_ADDR")
+		if addr == "" {
+			logger.Fatal("DENYLIST_ADDR ENV is not set, set DENYLIST_BACKEND=memory to run a single replica without Redis")
+		}
+		d := &RedisDenylist{}
+		if err := d.Connect(addr); err != nil {
+			logger.Fatal("Unable to connect to the denylist", zap.String("Error: ", err.Error()))
+		}
+		return d
+	case "memory":
+		logger.Warn("Using in-memory denylist, tokens revoked by this replica stay valid on the others")
+		return NewMemoryDenylist()
+	default:
+		logger.Fatal("Unknown DENYLIST_BACKEND", zap.String("backend", backend))
+		return nil
+	}
+}
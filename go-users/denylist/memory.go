@@ -0,0 +1,46 @@
+package denylist
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryDenylist is a Denylist kept in process memory.
+type MemoryDenylist struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{entries: make(map[string]time.Time)}
+}
+
+func (d *MemoryDenylist) Add(ctx context.Context, id string, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// revocations are rare enough to sweep the expired entries on each
+	now := time.Now()
+	for key, expiresAt := range d.entries {
+		if !expiresAt.After(now) {
+			delete(d.entries, key)
+		}
+	}
+
+	d.entries[id] = now.Add(ttl)
+	return nil
+}
+
+func (d *MemoryDenylist) Contains(ctx context.Context, ids ...string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for _, id := range ids {
+		if expiresAt, ok := d.entries[id]; ok && expiresAt.After(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,51 @@
+package denylist
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "denylist:"
+
+// RedisDenylist is a Denylist shared by all the replicas through Redis.
+type RedisDenylist struct {
+	Client *redis.Client
+}
+
+func (d *RedisDenylist) Connect(addr string) error {
+	opt, err := redis.ParseURL(addr)
+	if err != nil {
+		return err
+	}
+
+	client := redis.NewClient(opt)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return err
+	}
+
+	d.Client = client
+	return nil
+}
+
+func (d *RedisDenylist) Add(ctx context.Context, id string, ttl time.Duration) error {
+	return d.Client.Set(ctx, keyPrefix+id, 1, ttl).Err()
+}
+
+func (d *RedisDenylist) Contains(ctx context.Context, ids ...string) (bool, error) {
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = keyPrefix + id
+	}
+
+	found, err := d.Client.Exists(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+	return found > 0, nil
+}
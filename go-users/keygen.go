@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"go-users/tokens"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const keygenUsage = "Usage: go-users keygen <dir>"
+
+// runKeygen implements the keygen subcommand. It writes a new Ed25519 signing
+// key named after the current month to the given directory, unless the
+// directory already holds keys, so that it can run before every start of the
+// replicas sharing the directory.
+func runKeygen(args []string) {
+	logger, _ := zap.NewDevelopment()
+	if len(args) != 1 {
+		logger.Fatal(keygenUsage)
+	}
+
+	existing, err := filepath.Glob(filepath.Join(args[0], "*.pem"))
+	if err != nil {
+		logger.Fatal("Error occured while listing the keys", zap.String("Error: ", err.Error()))
+	}
+	if len(existing) > 0 {
+		fmt.Println("keys already exist")
+		return
+	}
+
+	path := filepath.Join(args[0], time.Now().Format("2006-01")+".pem")
+	if err := tokens.GenerateKeyFile(path); err != nil {
+		logger.Fatal("Error occured while generating the key", zap.String("Error: ", err.Error()))
+	}
+	fmt.Printf("generated %v\n", path)
+}
@@ -0,0 +1,48 @@
+// Package mail sends the e-mails of go-users, such as password reset links.
+package mail
+
+import (
+	"context"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// Message is a plain text e-mail.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers e-mails.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New creates the sender selected by the MAIL_BACKEND ENV: "log" (the
+// default) writes the e-mails to the log, "file" appends them to MAIL_FILE
+// and "smtp" sends them through SMTP_ADDR. The first two are meant for local
+// use only.
+func New(logger *zap.Logger) Sender {
+	switch backend := os.Getenv("MAIL_BACKEND"); backend {
+	case "", "log":
+		return &LogSender{Logger: logger}
+	case "file":
+		path := os.Getenv("MAIL_FILE")
+		if path == "" {
+			path = "./data/mail.log"
+		}
+		return &FileSender{Path: path}
+	case "smtp":
+		return &SMTPSender{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+	default:
+		logger.Fatal("Unknown MAIL_BACKEND", zap.String("backend", backend))
+		return nil
+	}
+}
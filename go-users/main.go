@@ -1,6 +1,8 @@
 package main
 
 import (
+	"go-users/denylist"
+	"go-users/mail"
 	"go-users/server"
 	"go-users/storage"
 	"go-users/tokens"
@@ -12,12 +14,18 @@ import (
 func setupService() server.Server {
 	logger, _ := zap.NewDevelopment()
 	logger.Info("Starting go-users...")
-	storage := &storage.Storage{Logger: logger}
-	storage.Init(os.Getenv("DB_ADDR"))
+	storage := storage.New(logger)
 
-	tokenizer := &tokens.JwtTokenizer{Logger: logger}
+	keys, err := tokens.LoadKeys(logger)
+	if err != nil {
+		logger.Fatal("Unable to load the token keys", zap.String("Error: ", err.Error()))
+	}
 
-	server := server.CreateServer(storage, tokenizer, logger)
+	tokenizer := &tokens.JwtTokenizer{Logger: logger, Denylist: denylist.New(logger), Keys: keys}
+
+	mailer := mail.New(logger)
+
+	server := server.CreateServer(storage, tokenizer, mailer, logger)
 	server.SetupRoutes()
 	logger.Info("Go-users is ready to be launched")
 
@@ -25,6 +33,15 @@ func setupService() server.Server {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "keygen" {
+		runKeygen(os.Args[2:])
+		return
+	}
+
 	usersService := setupService()
 	usersService.Engine.Run(":5000")
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"go-users/storage"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const migrateUsage = "Usage: go-users migrate up|down|status"
+
+// runMigrate implements the migrate subcommand. "up" applies the pending
+// migrations, "down" rolls back the latest one and "status" lists them all.
+func runMigrate(args []string) {
+	logger, _ := zap.NewDevelopment()
+	if len(args) != 1 {
+		logger.Fatal(migrateUsage)
+	}
+	if os.Getenv("DB_ADDR") == "" {
+		logger.Fatal("DB_ADDR ENV is not set")
+	}
+
+	st := &storage.PostgreStorage{Logger: logger}
+	st.Connect(os.Getenv("DB_ADDR"))
+
+	migrator, err := st.Migrator()
+	if err != nil {
+		logger.Fatal("Error occured while loading migrations", zap.String("Error: ", err.Error()))
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			logger.Fatal("Error occured while applying migrations", zap.String("Error: ", err.Error()))
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		migration, err := migrator.Down()
+		if err != nil {
+			logger.Fatal("Error occured while rolling back migration", zap.String("Error: ", err.Error()))
+		}
+		if migration == nil {
+			fmt.Println("no applied migrations")
+			return
+		}
+		fmt.Printf("rolled back %04d_%s\n", migration.Version, migration.Name)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			logger.Fatal("Error occured while reading migrations status", zap.String("Error: ", err.Error()))
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		logger.Fatal(migrateUsage)
+	}
+}
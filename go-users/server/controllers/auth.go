@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"go-users/storage"
+	"go-users/tokens"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUser validates the caller's token cookies, refreshing them if the
+// access token has expired. On failure it writes 401 and returns false.
+func currentUser(c *gin.Context, storage storage.Storage, tokenizer tokens.Tokenizer) (*tokens.ValidationResults, bool) {
+	access_token, err := c.Cookie("access_token")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authorized / invalid tokens"})
+		return nil, false
+	}
+	refresh_token, err := c.Cookie("refresh_token")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authorized / invalid tokens"})
+		return nil, false
+	}
+
+	res, err := tokens.ValidateUser(storage, tokenizer, access_token, refresh_token, clientOf(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if res.Access_Token != "" {
+		c.SetCookie("access_token", res.Access_Token, 3600*24, "/", "localhost", false, true)
+	}
+	if res.Refresh_Token != "" {
+		c.SetCookie("refresh_token", res.Refresh_Token, 3600*24*7, "/", "localhost", false, true)
+	}
+
+	return res, true
+}
+
+// clientOf describes the device behind the request for its session. Requests
+// proxied by go-posts carry the original client in X-Forwarded-For and
+// User-Agent.
+func clientOf(c *gin.Context) tokens.Client {
+	return tokens.Client{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
@@ -2,28 +2,34 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go-users/mail"
 	"go-users/storage"
 	"go-users/storage/models"
 	"go-users/tokens"
 	"io"
 	"net/http"
 	"os"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type signupDto struct {
 	Username string `json:"username" binding:"required,min=4,max=32"`
 	Password string `json:"password" binding:"required,min=8,max=32"`
 	Email    string `json:"email" binding:"required,email"`
+
+	// DeviceName labels the session in the sessions list, e.g. "Work laptop".
+	DeviceName string `json:"device_name" binding:"max=64"`
 }
 
-func SignUp(storage *storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+// SignUp creates the user and e-mails them a verification link, their e-mail
+// address stays unverified until it is followed.
+func SignUp(store storage.Storage, tokenizer tokens.Tokenizer, mailer mail.Sender, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//validating request
 		var dto signupDto
@@ -37,52 +43,58 @@ func SignUp(storage *storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Lo
 		if err != nil {
 			logger.Error("Error occured while hashing the password", zap.String("Error: ", err.Error()))
 			c.JSON(500, gin.H{"error": "Internal server error"})
-		}
-
-		// creating refresh token
-		refreshToken, err := tokenizer.NewRefreshToken(jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour * 24 * 7).Unix()})
-		if err != nil {
-			logger.Error("Error occured while creating the refresh token", zap.String("Error: ", err.Error()))
-			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
 		}
 
 		// creating new user
 		new_user := &models.User{
-			Username:     dto.Username,
-			Password:     string(hash),
-			Email:        dto.Email,
-			RefreshToken: refreshToken,
+			Username: dto.Username,
+			Password: string(hash),
+			Email:    dto.Email,
 		}
 		// saving new user
-		new_user_id, err := storage.CreateUser(new_user)
+		_, err = store.CreateUser(new_user)
+		if errors.Is(err, storage.ErrDuplicateUser) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			logger.Error("Error occured while creating the user", zap.String("Error: ", err.Error()))
 			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
 		}
 
-		// creating access token
-		accessToken, err := tokenizer.NewAccessToken(tokens.UserClaims{Id: fmt.Sprint(new_user_id), Username: new_user.Username, Email: new_user.Email, StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour * 24).Unix()}})
+		go func() {
+			if err := sendVerification(store, mailer, new_user); err != nil {
+				logger.Error("Error occured while sending the verification e-mail", zap.String("Error: ", err.Error()))
+			}
+		}()
+
+		// signing in on the device
+		session, err := tokens.StartSession(store, tokenizer, new_user, clientOf(c), dto.DeviceName)
 		if err != nil {
-			logger.Error("Error occured while creating the access token", zap.String("Error: ", err.Error()))
+			logger.Error("Error occured while creating the session", zap.String("Error: ", err.Error()))
 			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
 		}
 
 		// setting cookies
-		c.SetCookie("access_token", accessToken, 3600*24, "/", "localhost", false, true)
-		c.SetCookie("refresh_token", refreshToken, 3600*24*7, "/", "localhost", false, true)
+		c.SetCookie("access_token", session.Access_Token, 3600*24, "/", "localhost", false, true)
+		c.SetCookie("refresh_token", session.Refresh_Token, 3600*24*7, "/", "localhost", false, true)
 
-		resp := gin.H{"username": new_user.Username, "email": new_user.Email}
+		resp := gin.H{"username": new_user.Username, "email": new_user.Email, "email_verified": false}
 
 		c.JSON(201, resp)
 	}
 }
 
 type SignInDto struct {
-	Username string `json:"username" binding:"required,min=4,max=32"`
-	Password string `json:"password" binding:"required,min=8,max=32"`
+	Username   string `json:"username" binding:"required,min=4,max=32"`
+	Password   string `json:"password" binding:"required,min=8,max=32"`
+	DeviceName string `json:"device_name" binding:"max=64"`
 }
 
-func SignIn(storage *storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+func SignIn(storage storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//validating request
 		var dto SignInDto
@@ -104,31 +116,46 @@ func SignIn(storage *storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Lo
 			return
 		}
 
-		// creating access token
-		accessToken, err := tokenizer.NewAccessToken(tokens.UserClaims{Id: fmt.Sprint(user.ID), Username: user.Username, Email: user.Email, StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour * 24).Unix()}})
+		// signing in on a new device
+		session, err := tokens.StartSession(storage, tokenizer, user, clientOf(c), dto.DeviceName)
 		if err != nil {
-			logger.Error("Error occured while creating the access token", zap.String("Error: ", err.Error()))
+			logger.Error("Error occured while creating the session", zap.String("Error: ", err.Error()))
 			c.JSON(500, gin.H{"error": "Internal server error"})
+			return
 		}
 
-		refreshToken, err := tokenizer.NewRefreshToken(jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour * 24 * 7).Unix()})
-		if err != nil {
-			logger.Error("Error occured while creating the refresh token", zap.String("Error: ", err.Error()))
-			c.JSON(500, gin.H{"error": "Internal server error"})
+		c.SetCookie("access_token", session.Access_Token, 3600*24, "/", "localhost", false, true)
+		c.SetCookie("refresh_token", session.Refresh_Token, 3600*24*7, "/", "localhost", false, true)
+
+		resp := gin.H{"username": user.Username, "email": user.Email, "email_verified": user.EmailVerifiedAt != nil}
+
+		c.JSON(200, resp)
+	}
+}
+
+// SignOut ends the session of the caller and revokes their access token.
+func SignOut(storage storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c, storage, tokenizer)
+		if !ok {
+			return
 		}
 
-		err = storage.UpdateUserRefreshToken(user.Username, refreshToken)
-		if err != nil {
-			logger.Debug("Error occured when creating refresh token", zap.String("Error: ", err.Error()))
-			c.JSON(500, gin.H{"error": "Internal server error"})
+		if user.SessionID != "" {
+			err := storage.DeleteSession(uint(user.User_id), user.SessionID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				logger.Error("Error occured while deleting the session", zap.String("Error: ", err.Error()))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				return
+			}
 		}
 
-		c.SetCookie("access_token", accessToken, 3600*24, "/", "localhost", false, true)
-		c.SetCookie("refresh_token", refreshToken, 3600*24*7, "/", "localhost", false, true)
+		revokeAccessTokens(tokenizer, logger, user.TokenID, user.SessionID)
 
-		resp := gin.H{"username": user.Username, "email": user.Email}
+		c.SetCookie("access_token", "", -1, "/", "localhost", false, true)
+		c.SetCookie("refresh_token", "", -1, "/", "localhost", false, true)
 
-		c.JSON(200, resp)
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	}
 }
 
@@ -140,7 +167,7 @@ type GetStatsPayload struct {
 	Amount int64 `json:"amount"`
 }
 
-func GetStats(storage *storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+func GetStats(storage storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req GetStatsDto
 		if err := c.ShouldBindQuery(&req); err != nil {
@@ -159,7 +186,7 @@ func GetStats(storage *storage.Storage, tokenizer tokens.Tokenizer, logger *zap.
 			return
 		}
 
-		res, err := tokens.ValidateUser(storage, tokenizer, access_token, refresh_token)
+		res, err := tokens.ValidateUser(storage, tokenizer, access_token, refresh_token, clientOf(c))
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, err.Error())
 			return
@@ -168,6 +195,10 @@ func GetStats(storage *storage.Storage, tokenizer tokens.Tokenizer, logger *zap.
 		// Make a request to users with specified user id
 		targetURL := fmt.Sprintf("http://%v/posts/count?id=%v", os.Getenv("POSTS_LOADBALANCER"), res.User_id)
 		resp, err := http.Get(targetURL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
 		defer resp.Body.Close()
 
 		var payload GetStatsPayload
@@ -183,8 +214,18 @@ func GetStats(storage *storage.Storage, tokenizer tokens.Tokenizer, logger *zap.
 			return
 		}
 
+		user, err := storage.GetUserByID(res.User_id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+
 		// TODO Should also send request to likes to get amount of likes
 
-		c.JSON(http.StatusOK, gin.H{"posts_amount": payload.Amount})
+		c.JSON(http.StatusOK, gin.H{
+			"posts_amount":    payload.Amount,
+			"followers_count": user.FollowersCount,
+			"following_count": user.FollowingCount,
+		})
 	}
 }
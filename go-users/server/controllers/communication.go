@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"go-users/storage"
 	"go-users/tokens"
@@ -10,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/shirou/gopsutil/cpu"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type AuthDto struct {
@@ -17,14 +19,17 @@ type AuthDto struct {
 	Refresh_token string `json:"refresh_token" binding:"required"`
 }
 
+// AuthSuccessResp tells go-posts who the caller is. Email_verified lets it
+// block unverified accounts from posting.
 type AuthSuccessResp struct {
-	User_id       int    `json:"user_id"`
-	Username      string `json:"username"`
-	Access_token  string `json:"access_token"`
-	Refresh_token string `json:"refresh_token"`
+	User_id        int    `json:"user_id"`
+	Username       string `json:"username"`
+	Email_verified bool   `json:"email_verified"`
+	Access_token   string `json:"access_token"`
+	Refresh_token  string `json:"refresh_token"`
 }
 
-func Authenticate(storage *storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+func Authenticate(storage storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var authDto AuthDto
 		// Bind the request body to the AuthDto struct
@@ -33,22 +38,44 @@ func Authenticate(storage *storage.Storage, tokenizer tokens.Tokenizer, logger *
 			return
 		}
 
-		res, err := tokens.ValidateUser(storage, tokenizer, authDto.Access_token, authDto.Refresh_token)
+		res, err := tokens.ValidateUser(storage, tokenizer, authDto.Access_token, authDto.Refresh_token, clientOf(c))
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		user, err := storage.GetUserByID(res.User_id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnauthorized, "Invalid tokens")
+			return
+		}
+		if err != nil {
+			logger.Error("Error occured while getting the user", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
 		}
 
 		resp := &AuthSuccessResp{
-			User_id:       res.User_id,
-			Username:      res.Username,
-			Access_token:  res.Access_Token,
-			Refresh_token: res.Refresh_Token,
+			User_id:        res.User_id,
+			Username:       res.Username,
+			Email_verified: user.EmailVerifiedAt != nil,
+			Access_token:   res.Access_Token,
+			Refresh_token:  res.Refresh_Token,
 		}
 
 		c.JSON(http.StatusOK, resp)
 	}
 }
 
+// GetJWKS publishes the public keys of the tokens, so that other services can
+// verify them without asking go-users. Keys change only on rotation.
+func GetJWKS(tokenizer tokens.Tokenizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, tokenizer.JWKS())
+	}
+}
+
 func GetLoadstate() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		percent, err := cpu.Percent(time.Second, false)
@@ -65,7 +92,7 @@ type GetUserByIdDto struct {
 	id int `form:"id" binding:"required"`
 }
 
-func GetUserById(storage *storage.Storage, logger *zap.Logger) gin.HandlerFunc {
+func GetUserById(storage storage.Storage, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var dto GetUserByIdDto
 		if err := c.ShouldBindQuery(&dto); err != nil {
@@ -82,21 +109,30 @@ func GetUserById(storage *storage.Storage, logger *zap.Logger) gin.HandlerFunc {
 }
 
 type GetUserByUsernameDto struct {
-	username string `form:"username" binding:"required"`
+	Username string `form:"username" binding:"required"`
 }
 
-func GetUserByUsername(storage *storage.Storage, logger *zap.Logger) gin.HandlerFunc {
+// GetUserByUsername is used by go-posts to resolve the users mentioned in
+// posts.
+func GetUserByUsername(storage storage.Storage, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var dto GetUserByUsernameDto
-		if err := c.ShouldBindJSON(&dto); err != nil {
-			c.JSON(http.StatusBadRequest, err.Error())
+		if err := c.ShouldBindQuery(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 
-		user, err := storage.GetUserByUsername(dto.username)
+		user, err := storage.GetUserByUsername(dto.Username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusNoContent, err.Error())
+			logger.Error("Error occured while getting the user", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
 		}
 
-		c.JSON(http.StatusOK, user.Username)
+		c.JSON(http.StatusOK, Author{ID: user.ID, Username: user.Username, DisplayName: user.DisplayName})
 	}
 }
@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"errors"
+	"go-users/storage"
+	"go-users/storage/models"
+	"go-users/tokens"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PublicUser is the part of a user that can be shown to other users.
+type PublicUser struct {
+	ID             uint   `json:"id"`
+	Username       string `json:"username"`
+	FollowersCount uint   `json:"followers_count"`
+	FollowingCount uint   `json:"following_count"`
+}
+
+func toPublicUsers(users []models.User) []PublicUser {
+	res := make([]PublicUser, len(users))
+	for i, user := range users {
+		res[i] = PublicUser{
+			ID:             user.ID,
+			Username:       user.Username,
+			FollowersCount: user.FollowersCount,
+			FollowingCount: user.FollowingCount,
+		}
+	}
+	return res
+}
+
+type FollowDto struct {
+	User_Id uint `form:"id" binding:"required,min=1"`
+}
+
+func Follow(store storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto FollowDto
+		if err := c.ShouldBindQuery(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, ok := currentUser(c, store, tokenizer)
+		if !ok {
+			return
+		}
+
+		err := store.Follow(uint(user.User_id), dto.User_Id)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		case errors.Is(err, storage.ErrSelfFollow):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, storage.ErrAlreadyFollowing):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			logger.Error("Error occured while following the user", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		go notifyFollow(uint(user.User_id), dto.User_Id, logger)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	}
+}
+
+func Unfollow(store storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto FollowDto
+		if err := c.ShouldBindQuery(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, ok := currentUser(c, store, tokenizer)
+		if !ok {
+			return
+		}
+
+		err := store.Unfollow(uint(user.User_id), dto.User_Id)
+		switch {
+		case errors.Is(err, storage.ErrNotFollowing):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			logger.Error("Error occured while unfollowing the user", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	}
+}
+
+type FollowListDto struct {
+	User_Id  uint `form:"id" binding:"required,min=1"`
+	PageID   uint `form:"pageid" binding:"required,min=1"`
+	PageSize uint `form:"pagesize" binding:"required,min=1,max=100"`
+}
+
+func GetFollowers(store storage.Storage, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto FollowListDto
+		if err := c.ShouldBindQuery(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		users, err := store.GetFollowers(dto.User_Id, int(dto.PageSize), int((dto.PageID-1)*dto.PageSize))
+		if err != nil {
+			logger.Error("Error occured while getting followers", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, toPublicUsers(users))
+	}
+}
+
+func GetFollowing(store storage.Storage, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto FollowListDto
+		if err := c.ShouldBindQuery(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		users, err := store.GetFollowing(dto.User_Id, int(dto.PageSize), int((dto.PageID-1)*dto.PageSize))
+		if err != nil {
+			logger.Error("Error occured while getting followed users", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, toPublicUsers(users))
+	}
+}
+
+type GetFollowCountsDto struct {
+	User_Id uint `form:"id" binding:"required,min=1"`
+}
+
+func GetFollowCounts(store storage.Storage, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto GetFollowCountsDto
+		if err := c.ShouldBindQuery(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, err := store.GetUserByID(int(dto.User_Id))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err != nil {
+			logger.Error("Error occured while getting the user", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"followers_count": user.FollowersCount, "following_count": user.FollowingCount})
+	}
+}
+
+type FollowIDsDto struct {
+	User_Id uint `form:"id" binding:"required,min=1"`
+}
+
+// GetFollowerIDs is used by go-posts to fan a new post out to the timelines
+// of the author's followers.
+func GetFollowerIDs(store storage.Storage, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto FollowIDsDto
+		if err := c.ShouldBindQuery(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ids, err := store.GetFollowerIDs(dto.User_Id)
+		if err != nil {
+			logger.Error("Error occured while getting follower ids", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, ids)
+	}
+}
+
+// GetFollowedUsers is used by go-posts to build the home timeline of a user.
+func GetFollowedUsers(store storage.Storage, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto FollowIDsDto
+		if err := c.ShouldBindQuery(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		users, err := store.GetFollowedUsers(dto.User_Id)
+		if err != nil {
+			logger.Error("Error occured while getting followed users", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, users)
+	}
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-users/mail"
+	"go-users/storage"
+	"go-users/storage/models"
+	"go-users/tokens"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// PasswordResetTTL is how long a password reset link stays valid, set by the
+// PASSWORD_RESET_TTL ENV.
+var PasswordResetTTL = envDuration("PASSWORD_RESET_TTL", time.Hour)
+
+// resetLink is the page of the frontend where the token is redeemed, set by
+// the PASSWORD_RESET_URL ENV.
+func resetLink(token string) string {
+	base := os.Getenv("PASSWORD_RESET_URL")
+	if base == "" {
+		base = "http://localhost:3000/password/reset"
+	}
+	return base + "?token=" + url.QueryEscape(token)
+}
+
+type ForgotPasswordDto struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPassword e-mails a password reset link to the owner of the address.
+// It answers the same whether the address is known or not, so it can not be
+// used to find out who is registered.
+func ForgotPassword(store storage.Storage, mailer mail.Sender, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto ForgotPasswordDto
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		go sendPasswordReset(store, mailer, dto.Email, logger)
+
+		c.JSON(http.StatusAccepted, gin.H{"message": "If the address is registered, a reset link has been sent to it"})
+	}
+}
+
+func sendPasswordReset(store storage.Storage, mailer mail.Sender, email string, logger *zap.Logger) {
+	user, err := store.GetUserByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	if err != nil {
+		logger.Error("Error occured while getting the user", zap.String("Error: ", err.Error()))
+		return
+	}
+
+	token, hash, err := newOneTimeToken()
+	if err != nil {
+		logger.Error("Error occured while creating the reset token", zap.String("Error: ", err.Error()))
+		return
+	}
+
+	reset := &models.PasswordReset{UserID: user.ID, TokenHash: hash, ExpiresAt: time.Now().Add(PasswordResetTTL)}
+	if err := store.CreatePasswordReset(reset); err != nil {
+		logger.Error("Error occured while saving the reset token", zap.String("Error: ", err.Error()))
+		return
+	}
+
+	err = mailer.Send(context.Background(), mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nuse the link below to choose a new password. It is valid for %s and can be used once.\n\n%s\n\nIf you did not ask for it, ignore this e-mail.",
+			user.Username, PasswordResetTTL, resetLink(token)),
+	})
+	if err != nil {
+		logger.Error("Error occured while sending the reset e-mail", zap.String("Error: ", err.Error()))
+	}
+}
+
+type ResetPasswordDto struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=32"`
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. The
+// user is signed out everywhere, their access tokens included, and has to
+// sign in with the new password.
+func ResetPassword(store storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto ResetPasswordDto
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(dto.Password), bcrypt.DefaultCost)
+		if err != nil {
+			logger.Error("Error occured while hashing the password", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		_, sessionIDs, err := store.ResetPassword(hashOneTimeToken(dto.Token), string(hash), time.Now())
+		if errors.Is(err, storage.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			logger.Error("Error occured while resetting the password", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		revokeAccessTokens(tokenizer, logger, sessionIDs...)
+
+		c.SetCookie("access_token", "", -1, "/", "localhost", false, true)
+		c.SetCookie("refresh_token", "", -1, "/", "localhost", false, true)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	}
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"fmt"
+	"go-users/storage"
+	"go-users/tokens"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Author is the public identity of a user as it is embedded into posts.
+type Author struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+}
+
+type UpdateProfileDto struct {
+	DisplayName string `json:"display_name" binding:"max=50"`
+}
+
+func UpdateProfile(storage storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto UpdateProfileDto
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, ok := currentUser(c, storage, tokenizer)
+		if !ok {
+			return
+		}
+
+		displayName := strings.TrimSpace(dto.DisplayName)
+		if err := storage.UpdateDisplayName(uint(user.User_id), displayName); err != nil {
+			logger.Error("Error occured while updating the profile", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		go invalidateAuthor(uint(user.User_id), logger)
+
+		c.JSON(http.StatusOK, Author{ID: uint(user.User_id), Username: user.Username, DisplayName: displayName})
+	}
+}
+
+var postsClient = &http.Client{Timeout: 5 * time.Second}
+
+// postInternal calls an internal callback of go-posts, authenticated with the
+// INTERNAL_TOKEN ENV shared by the services.
+func postInternal(targetURL string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", os.Getenv("INTERNAL_TOKEN"))
+	return postsClient.Do(req)
+}
+
+// invalidateAuthor tells go-posts to drop its cached copy of the author, so
+// that posts stop showing the old profile.
+func invalidateAuthor(id uint, logger *zap.Logger) {
+	targetURL := fmt.Sprintf("http://%v/posts/authors/invalidate?id=%v", os.Getenv("POSTS_LOADBALANCER"), id)
+	resp, err := postInternal(targetURL)
+	if err != nil {
+		logger.Error("Unable to invalidate cached author", zap.String("Error: ", err.Error()))
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		logger.Error("Unable to invalidate cached author", zap.Int("Status: ", resp.StatusCode))
+	}
+}
+
+// notifyFollow tells go-posts to notify the followee of their new follower.
+func notifyFollow(followerID uint, followeeID uint, logger *zap.Logger) {
+	targetURL := fmt.Sprintf("http://%v/posts/notifications/follow?follower_id=%v&followee_id=%v", os.Getenv("POSTS_LOADBALANCER"), followerID, followeeID)
+	resp, err := postInternal(targetURL)
+	if err != nil {
+		logger.Error("Unable to notify the followed user", zap.String("Error: ", err.Error()))
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		logger.Error("Unable to notify the followed user", zap.Int("Status: ", resp.StatusCode))
+	}
+}
+
+type GetUsersBatchDto struct {
+	IDs string `form:"ids" binding:"required"`
+}
+
+// maxBatchSize limits how many users can be resolved by a single request.
+const maxBatchSize = 100
+
+// GetUsersBatch resolves a comma separated list of user ids into authors.
+// Unknown ids are skipped.
+func GetUsersBatch(storage storage.Storage, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto GetUsersBatchDto
+		if err := c.ShouldBindQuery(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		parts := strings.Split(dto.IDs, ",")
+		if len(parts) > maxBatchSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d ids are allowed", maxBatchSize)})
+			return
+		}
+
+		ids := make([]uint, 0, len(parts))
+		for _, part := range parts {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id: " + part})
+				return
+			}
+			ids = append(ids, uint(id))
+		}
+
+		users, err := storage.GetUsersByIDs(ids)
+		if err != nil {
+			logger.Error("Error occured while getting users", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		authors := make([]Author, len(users))
+		for i, user := range users {
+			authors[i] = Author{ID: user.ID, Username: user.Username, DisplayName: user.DisplayName}
+		}
+
+		c.JSON(http.StatusOK, authors)
+	}
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"errors"
+	"go-users/storage"
+	"go-users/tokens"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// SessionView is a signed in device as the user sees it. Current marks the
+// session the request was made from.
+type SessionView struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
+}
+
+func GetSessions(storage storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c, storage, tokenizer)
+		if !ok {
+			return
+		}
+
+		sessions, err := storage.GetUserSessions(uint(user.User_id), time.Now())
+		if err != nil {
+			logger.Error("Error occured while getting the sessions", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		views := make([]SessionView, 0, len(sessions))
+		for _, session := range sessions {
+			views = append(views, SessionView{
+				ID:         session.ID,
+				DeviceName: session.DeviceName,
+				IP:         session.IP,
+				UserAgent:  session.UserAgent,
+				CreatedAt:  session.CreatedAt,
+				LastUsedAt: session.LastUsedAt,
+				Current:    session.ID == user.SessionID,
+			})
+		}
+
+		c.JSON(http.StatusOK, views)
+	}
+}
+
+// DeleteSession signs the user out on one of their devices.
+func DeleteSession(storage storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c, storage, tokenizer)
+		if !ok {
+			return
+		}
+
+		id := c.Param("id")
+		err := storage.DeleteSession(uint(user.User_id), id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		if err != nil {
+			logger.Error("Error occured while deleting the session", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		revokeAccessTokens(tokenizer, logger, id)
+
+		if id == user.SessionID {
+			c.SetCookie("access_token", "", -1, "/", "localhost", false, true)
+			c.SetCookie("refresh_token", "", -1, "/", "localhost", false, true)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	}
+}
+
+// DeleteSessions signs the user out everywhere, this device included.
+func DeleteSessions(storage storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c, storage, tokenizer)
+		if !ok {
+			return
+		}
+
+		sessionIDs, err := storage.DeleteUserSessions(uint(user.User_id))
+		if err != nil {
+			logger.Error("Error occured while deleting the sessions", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		revokeAccessTokens(tokenizer, logger, sessionIDs...)
+
+		c.SetCookie("access_token", "", -1, "/", "localhost", false, true)
+		c.SetCookie("refresh_token", "", -1, "/", "localhost", false, true)
+
+		c.JSON(http.StatusOK, gin.H{"revoked": len(sessionIDs)})
+	}
+}
+
+// revokeAccessTokens puts the given jtis or session IDs on the denylist. The
+// sessions are already gone by then, so a failure is only logged: the tokens
+// stay usable until they expire, but can not be refreshed.
+func revokeAccessTokens(tokenizer tokens.Tokenizer, logger *zap.Logger, ids ...string) {
+	if err := tokenizer.RevokeAccessTokens(ids...); err != nil {
+		logger.Error("Error occured while revoking the access tokens", zap.String("Error: ", err.Error()))
+	}
+}
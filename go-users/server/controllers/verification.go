@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-users/mail"
+	"go-users/storage"
+	"go-users/storage/models"
+	"go-users/tokens"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// How long a verification link stays valid and how often a user can ask for a
+// new one, set by the EMAIL_VERIFICATION_TTL and
+// EMAIL_VERIFICATION_RESEND_INTERVAL ENVs.
+var (
+	EmailVerificationTTL            = envDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	EmailVerificationResendInterval = envDuration("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute)
+)
+
+// verificationLink points at VerifyEmail, through the EMAIL_VERIFICATION_URL
+// ENV when the service is reached under another address.
+func verificationLink(token string) string {
+	base := os.Getenv("EMAIL_VERIFICATION_URL")
+	if base == "" {
+		base = "http://localhost:5000/users/verify"
+	}
+	return base + "?token=" + url.QueryEscape(token)
+}
+
+// sendVerification issues a new verification token to the user and e-mails
+// it.
+func sendVerification(store storage.Storage, mailer mail.Sender, user *models.User) error {
+	token, hash, err := newOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	verification := &models.EmailVerification{UserID: user.ID, TokenHash: hash, ExpiresAt: time.Now().Add(EmailVerificationTTL)}
+	if err := store.CreateEmailVerification(verification); err != nil {
+		return err
+	}
+
+	return mailer.Send(context.Background(), mail.Message{
+		To:      user.Email,
+		Subject: "Verify your e-mail address",
+		Body: fmt.Sprintf("Hi %s,\n\nopen the link below to verify your e-mail address. It is valid for %s.\n\n%s",
+			user.Username, EmailVerificationTTL, verificationLink(token)),
+	})
+}
+
+type VerifyEmailDto struct {
+	Token string `form:"token" binding:"required"`
+}
+
+// VerifyEmail redeems the token of a verification e-mail.
+func VerifyEmail(store storage.Storage, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var dto VerifyEmailDto
+		if err := c.ShouldBindQuery(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		_, err := store.VerifyEmail(hashOneTimeToken(dto.Token), time.Now())
+		if errors.Is(err, storage.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			logger.Error("Error occured while verifying the e-mail", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "E-mail address verified"})
+	}
+}
+
+// ResendVerification e-mails a new verification link to the caller, at most
+// once per EmailVerificationResendInterval.
+func ResendVerification(store storage.Storage, tokenizer tokens.Tokenizer, mailer mail.Sender, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, ok := currentUser(c, store, tokenizer)
+		if !ok {
+			return
+		}
+
+		user, err := store.GetUserByID(res.User_id)
+		if err != nil {
+			logger.Error("Error occured while getting the user", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if user.EmailVerifiedAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "E-mail address is already verified"})
+			return
+		}
+
+		latest, err := store.GetLatestEmailVerification(user.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error("Error occured while getting the last verification", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if latest != nil {
+			if wait := time.Until(latest.CreatedAt.Add(EmailVerificationResendInterval)); wait > 0 {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "Verification e-mail was sent recently, try again later"})
+				return
+			}
+		}
+
+		if err := sendVerification(store, mailer, user); err != nil {
+			logger.Error("Error occured while sending the verification e-mail", zap.String("Error: ", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Verification e-mail sent"})
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"go-users/mail"
 	"go-users/server/controllers"
 	"go-users/storage"
 	"go-users/tokens"
@@ -11,23 +12,45 @@ import (
 
 type Server struct {
 	Engine    *gin.Engine
-	Storage   *storage.Storage
+	Storage   storage.Storage
 	Tokenizer tokens.Tokenizer
+	Mailer    mail.Sender
 	Logger    *zap.Logger
 }
 
-func CreateServer(s *storage.Storage, tokenizer tokens.Tokenizer, logger *zap.Logger) *Server {
-	return &Server{Engine: gin.Default(), Storage: s, Tokenizer: tokenizer, Logger: logger}
+func CreateServer(s storage.Storage, tokenizer tokens.Tokenizer, mailer mail.Sender, logger *zap.Logger) *Server {
+	return &Server{Engine: gin.Default(), Storage: s, Tokenizer: tokenizer, Mailer: mailer, Logger: logger}
 }
 
 func (s *Server) SetupRoutes() {
-	s.Engine.POST("/users/signup", controllers.SignUp(s.Storage, s.Tokenizer, s.Logger))
+	s.Engine.POST("/users/signup", controllers.SignUp(s.Storage, s.Tokenizer, s.Mailer, s.Logger))
 	s.Engine.POST("/users/signin", controllers.SignIn(s.Storage, s.Tokenizer, s.Logger))
+	s.Engine.POST("/users/signout", controllers.SignOut(s.Storage, s.Tokenizer, s.Logger))
+	s.Engine.GET("/users/.well-known/jwks.json", controllers.GetJWKS(s.Tokenizer))
 	s.Engine.GET("/users/stats", controllers.GetStats(s.Storage, s.Tokenizer, s.Logger))
+	s.Engine.PATCH("/users/profile", controllers.UpdateProfile(s.Storage, s.Tokenizer, s.Logger))
+
+	s.Engine.POST("/users/password/forgot", controllers.ForgotPassword(s.Storage, s.Mailer, s.Logger))
+	s.Engine.POST("/users/password/reset", controllers.ResetPassword(s.Storage, s.Tokenizer, s.Logger))
+	s.Engine.GET("/users/verify", controllers.VerifyEmail(s.Storage, s.Logger))
+	s.Engine.POST("/users/verify/resend", controllers.ResendVerification(s.Storage, s.Tokenizer, s.Mailer, s.Logger))
+
+	s.Engine.GET("/users/sessions", controllers.GetSessions(s.Storage, s.Tokenizer, s.Logger))
+	s.Engine.DELETE("/users/sessions/:id", controllers.DeleteSession(s.Storage, s.Tokenizer, s.Logger))
+	s.Engine.DELETE("/users/sessions", controllers.DeleteSessions(s.Storage, s.Tokenizer, s.Logger))
+
+	s.Engine.POST("/users/follow", controllers.Follow(s.Storage, s.Tokenizer, s.Logger))
+	s.Engine.DELETE("/users/unfollow", controllers.Unfollow(s.Storage, s.Tokenizer, s.Logger))
+	s.Engine.GET("/users/followers", controllers.GetFollowers(s.Storage, s.Logger))
+	s.Engine.GET("/users/following", controllers.GetFollowing(s.Storage, s.Logger))
+	s.Engine.GET("/users/follows/count", controllers.GetFollowCounts(s.Storage, s.Logger))
 
 	//rabbitmq side -->
 	s.Engine.POST("/users/auth", controllers.Authenticate(s.Storage, s.Tokenizer, s.Logger))
 	s.Engine.GET("/users/getbyid", controllers.GetUserById(s.Storage, s.Logger))
 	s.Engine.GET("/users/getbyusername", controllers.GetUserByUsername(s.Storage, s.Logger))
+	s.Engine.GET("/users/batch", controllers.GetUsersBatch(s.Storage, s.Logger))
 	s.Engine.GET("/users/load", controllers.GetLoadstate())
+	s.Engine.GET("/users/followers/ids", controllers.GetFollowerIDs(s.Storage, s.Logger))
+	s.Engine.GET("/users/following/ids", controllers.GetFollowedUsers(s.Storage, s.Logger))
 }
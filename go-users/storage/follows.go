@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"errors"
+	"go-users/storage/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAlreadyFollowing = errors.New("user is already followed")
+	ErrNotFollowing     = errors.New("user is not followed")
+	ErrSelfFollow       = errors.New("users can not follow themselves")
+)
+
+// FollowedUser is a followed user together with the size of its audience,
+// which go-posts uses to pick the timeline fan-out strategy.
+type FollowedUser struct {
+	ID             uint `json:"id"`
+	FollowersCount uint `json:"followers_count"`
+}
+
+// Follow makes follower follow followee and updates the counters of both
+// users in the same transaction.
+func (st *PostgreStorage) Follow(followerID uint, followeeID uint) error {
+	if followerID == followeeID {
+		return ErrSelfFollow
+	}
+
+	return st.db.Transaction(func(tx *gorm.DB) error {
+		var followee models.User
+		if err := tx.Select("id").First(&followee, followeeID).Error; err != nil {
+			return err
+		}
+
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Follow{FollowerID: followerID, FolloweeID: followeeID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrAlreadyFollowing
+		}
+
+		return st.updateFollowCounters(tx, followerID, followeeID, "+")
+	})
+}
+
+// Unfollow removes the follow edge and updates the counters of both users in
+// the same transaction.
+func (st *PostgreStorage) Unfollow(followerID uint, followeeID uint) error {
+	return st.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&models.Follow{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFollowing
+		}
+
+		return st.updateFollowCounters(tx, followerID, followeeID, "-")
+	})
+}
+
+func (st *PostgreStorage) updateFollowCounters(tx *gorm.DB, followerID uint, followeeID uint, op string) error {
+	err := tx.Model(&models.User{}).Where("id = ?", followerID).
+		UpdateColumn("following_count", gorm.Expr("GREATEST(following_count "+op+" 1, 0)")).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&models.User{}).Where("id = ?", followeeID).
+		UpdateColumn("followers_count", gorm.Expr("GREATEST(followers_count "+op+" 1, 0)")).Error
+}
+
+// GetFollowers returns the users following userID, most recent first.
+func (st *PostgreStorage) GetFollowers(userID uint, limit int, offset int) ([]models.User, error) {
+	var users []models.User
+	err := st.db.
+		Joins("JOIN follows ON follows.follower_id = users.id").
+		Where("follows.followee_id = ?", userID).
+		Order("follows.created_at desc").
+		Limit(limit).Offset(offset).
+		Find(&users).Error
+	return users, err
+}
+
+// GetFollowing returns the users followed by userID, most recent first.
+func (st *PostgreStorage) GetFollowing(userID uint, limit int, offset int) ([]models.User, error) {
+	var users []models.User
+	err := st.db.
+		Joins("JOIN follows ON follows.followee_id = users.id").
+		Where("follows.follower_id = ?", userID).
+		Order("follows.created_at desc").
+		Limit(limit).Offset(offset).
+		Find(&users).Error
+	return users, err
+}
+
+// GetFollowerIDs returns the ids of all users following userID.
+func (st *PostgreStorage) GetFollowerIDs(userID uint) ([]uint, error) {
+	var ids []uint
+	err := st.db.Model(&models.Follow{}).Where("followee_id = ?", userID).Pluck("follower_id", &ids).Error
+	return ids, err
+}
+
+// GetFollowedUsers returns every user followed by userID with their followers
+// count.
+func (st *PostgreStorage) GetFollowedUsers(userID uint) ([]FollowedUser, error) {
+	var users []FollowedUser
+	err := st.db.Model(&models.User{}).
+		Select("users.id, users.followers_count").
+		Joins("JOIN follows ON follows.followee_id = users.id").
+		Where("follows.follower_id = ?", userID).
+		Scan(&users).Error
+	return users, err
+}
@@ -0,0 +1,471 @@
+package storage
+
+import (
+	"go-users/storage/models"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStorage is a thread-safe Storage kept in process memory. It enforces
+// the same uniqueness and ordering rules as PostgreStorage, but nothing
+// survives a restart.
+type MemoryStorage struct {
+	mu sync.RWMutex
+
+	lastUserID uint
+	users      map[uint]*models.User
+	follows    map[followKey]time.Time
+
+	lastResetID uint
+	resets      map[uint]*models.PasswordReset
+
+	lastVerificationID uint
+	verifications      map[uint]*models.EmailVerification
+
+	sessions map[string]*models.Session
+}
+
+type followKey struct {
+	followerID uint
+	followeeID uint
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		users:         make(map[uint]*models.User),
+		follows:       make(map[followKey]time.Time),
+		resets:        make(map[uint]*models.PasswordReset),
+		verifications: make(map[uint]*models.EmailVerification),
+		sessions:      make(map[string]*models.Session),
+	}
+}
+
+// user returns the first user matching the filter. The caller must hold the
+// lock.
+func (st *MemoryStorage) user(filter func(user *models.User) bool) (*models.User, bool) {
+	for _, user := range st.users {
+		if filter(user) {
+			return user, true
+		}
+	}
+	return nil, false
+}
+
+func (st *MemoryStorage) CreateUser(user *models.User) (uint, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	_, taken := st.user(func(u *models.User) bool {
+		return u.Username == user.Username || u.Email == user.Email
+	})
+	if taken {
+		return 0, ErrDuplicateUser
+	}
+
+	st.lastUserID++
+	user.ID = st.lastUserID
+	user.CreatedAt = time.Now()
+
+	stored := *user
+	st.users[user.ID] = &stored
+	return user.ID, nil
+}
+
+func (st *MemoryStorage) GetUserByUsername(username string) (*models.User, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	user, ok := st.user(func(u *models.User) bool { return u.Username == username })
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	res := *user
+	return &res, nil
+}
+
+func (st *MemoryStorage) GetUserByID(id int) (*models.User, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	user, ok := st.users[uint(id)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	res := *user
+	return &res, nil
+}
+
+func (st *MemoryStorage) GetUsersByIDs(ids []uint) ([]models.User, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var users []models.User
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if user, ok := st.users[id]; ok && !seen[id] {
+			seen[id] = true
+			users = append(users, *user)
+		}
+	}
+	return users, nil
+}
+
+func (st *MemoryStorage) UpdateDisplayName(id uint, displayName string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if user, ok := st.users[id]; ok {
+		user.DisplayName = displayName
+	}
+	return nil
+}
+
+func (st *MemoryStorage) Follow(followerID uint, followeeID uint) error {
+	if followerID == followeeID {
+		return ErrSelfFollow
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	followee, ok := st.users[followeeID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	key := followKey{followerID: followerID, followeeID: followeeID}
+	if _, ok := st.follows[key]; ok {
+		return ErrAlreadyFollowing
+	}
+	st.follows[key] = time.Now()
+
+	followee.FollowersCount++
+	if follower, ok := st.users[followerID]; ok {
+		follower.FollowingCount++
+	}
+	return nil
+}
+
+func (st *MemoryStorage) Unfollow(followerID uint, followeeID uint) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	key := followKey{followerID: followerID, followeeID: followeeID}
+	if _, ok := st.follows[key]; !ok {
+		return ErrNotFollowing
+	}
+	delete(st.follows, key)
+
+	if followee, ok := st.users[followeeID]; ok && followee.FollowersCount > 0 {
+		followee.FollowersCount--
+	}
+	if follower, ok := st.users[followerID]; ok && follower.FollowingCount > 0 {
+		follower.FollowingCount--
+	}
+	return nil
+}
+
+// edges returns the follow edges matching the filter, most recent first. The
+// caller must hold the lock.
+func (st *MemoryStorage) edges(filter func(follow models.Follow) bool) []models.Follow {
+	var edges []models.Follow
+	for key, createdAt := range st.follows {
+		follow := models.Follow{FollowerID: key.followerID, FolloweeID: key.followeeID, CreatedAt: createdAt}
+		if filter(follow) {
+			edges = append(edges, follow)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].CreatedAt.After(edges[j].CreatedAt)
+	})
+	return edges
+}
+
+// page returns the users at the other end of the edges, applying limit and
+// offset like the SQL queries do. The caller must hold the lock.
+func (st *MemoryStorage) page(edges []models.Follow, limit int, offset int, other func(follow models.Follow) uint) []models.User {
+	var users []models.User
+	for i, follow := range edges {
+		if i < offset {
+			continue
+		}
+		if limit >= 0 && len(users) >= limit {
+			break
+		}
+		if user, ok := st.users[other(follow)]; ok {
+			users = append(users, *user)
+		}
+	}
+	return users
+}
+
+func (st *MemoryStorage) GetFollowers(userID uint, limit int, offset int) ([]models.User, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	edges := st.edges(func(follow models.Follow) bool { return follow.FolloweeID == userID })
+	return st.page(edges, limit, offset, func(follow models.Follow) uint { return follow.FollowerID }), nil
+}
+
+func (st *MemoryStorage) GetFollowing(userID uint, limit int, offset int) ([]models.User, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	edges := st.edges(func(follow models.Follow) bool { return follow.FollowerID == userID })
+	return st.page(edges, limit, offset, func(follow models.Follow) uint { return follow.FolloweeID }), nil
+}
+
+func (st *MemoryStorage) GetFollowerIDs(userID uint) ([]uint, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var ids []uint
+	for key := range st.follows {
+		if key.followeeID == userID {
+			ids = append(ids, key.followerID)
+		}
+	}
+	return ids, nil
+}
+
+func (st *MemoryStorage) GetFollowedUsers(userID uint) ([]FollowedUser, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var users []FollowedUser
+	for key := range st.follows {
+		if key.followerID != userID {
+			continue
+		}
+		if user, ok := st.users[key.followeeID]; ok {
+			users = append(users, FollowedUser{ID: user.ID, FollowersCount: user.FollowersCount})
+		}
+	}
+	return users, nil
+}
+
+func (st *MemoryStorage) GetUserByEmail(email string) (*models.User, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	user, ok := st.user(func(u *models.User) bool { return u.Email == email })
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	res := *user
+	return &res, nil
+}
+
+func (st *MemoryStorage) CreatePasswordReset(reset *models.PasswordReset) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.lastResetID++
+	reset.ID = st.lastResetID
+	reset.CreatedAt = time.Now()
+
+	stored := *reset
+	st.resets[reset.ID] = &stored
+	return nil
+}
+
+func (st *MemoryStorage) ResetPassword(tokenHash string, passwordHash string, now time.Time) (*models.User, []string, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var reset *models.PasswordReset
+	for _, candidate := range st.resets {
+		if candidate.TokenHash == tokenHash && candidate.UsedAt == nil && candidate.ExpiresAt.After(now) {
+			reset = candidate
+			break
+		}
+	}
+	if reset == nil {
+		return nil, nil, ErrInvalidResetToken
+	}
+
+	user, ok := st.users[reset.UserID]
+	if !ok {
+		return nil, nil, ErrInvalidResetToken
+	}
+
+	for _, pending := range st.resets {
+		if pending.UserID == reset.UserID && pending.UsedAt == nil {
+			usedAt := now
+			pending.UsedAt = &usedAt
+		}
+	}
+
+	user.Password = passwordHash
+	sessionIDs := st.deleteUserSessions(user.ID)
+	res := *user
+	return &res, sessionIDs, nil
+}
+
+func (st *MemoryStorage) CreateEmailVerification(verification *models.EmailVerification) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.lastVerificationID++
+	verification.ID = st.lastVerificationID
+	verification.CreatedAt = time.Now()
+
+	stored := *verification
+	st.verifications[verification.ID] = &stored
+	return nil
+}
+
+func (st *MemoryStorage) GetLatestEmailVerification(userID uint) (*models.EmailVerification, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var latest *models.EmailVerification
+	for _, verification := range st.verifications {
+		if verification.UserID == userID && (latest == nil || verification.ID > latest.ID) {
+			latest = verification
+		}
+	}
+	if latest == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	res := *latest
+	return &res, nil
+}
+
+func (st *MemoryStorage) VerifyEmail(tokenHash string, now time.Time) (*models.User, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var verification *models.EmailVerification
+	for _, candidate := range st.verifications {
+		if candidate.TokenHash == tokenHash && candidate.UsedAt == nil && candidate.ExpiresAt.After(now) {
+			verification = candidate
+			break
+		}
+	}
+	if verification == nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	user, ok := st.users[verification.UserID]
+	if !ok {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	for _, pending := range st.verifications {
+		if pending.UserID == verification.UserID && pending.UsedAt == nil {
+			usedAt := now
+			pending.UsedAt = &usedAt
+		}
+	}
+
+	if user.EmailVerifiedAt == nil {
+		verifiedAt := now
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	res := *user
+	return &res, nil
+}
+
+func (st *MemoryStorage) CreateSession(session *models.Session) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for id, existing := range st.sessions {
+		if existing.UserID == session.UserID && !existing.ExpiresAt.After(session.LastUsedAt) {
+			delete(st.sessions, id)
+		}
+	}
+
+	if _, taken := st.sessions[session.ID]; taken {
+		return gorm.ErrDuplicatedKey
+	}
+	session.CreatedAt = time.Now()
+
+	stored := *session
+	st.sessions[session.ID] = &stored
+	return nil
+}
+
+func (st *MemoryStorage) GetSession(id string) (*models.Session, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	session, ok := st.sessions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	res := *session
+	return &res, nil
+}
+
+func (st *MemoryStorage) GetUserSessions(userID uint, now time.Time) ([]models.Session, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var sessions []models.Session
+	for _, session := range st.sessions {
+		if session.UserID == userID && session.ExpiresAt.After(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+func (st *MemoryStorage) RotateSession(tokenHash string, session *models.Session) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	stored, ok := st.sessions[session.ID]
+	if !ok || stored.RefreshTokenHash != tokenHash {
+		return ErrSessionRotated
+	}
+
+	rotatedAt := session.LastUsedAt
+	stored.PreviousTokenHash = tokenHash
+	stored.RefreshTokenHash = session.RefreshTokenHash
+	stored.RotatedAt = &rotatedAt
+	stored.IP = session.IP
+	stored.UserAgent = session.UserAgent
+	stored.LastUsedAt = session.LastUsedAt
+	stored.ExpiresAt = session.ExpiresAt
+	return nil
+}
+
+func (st *MemoryStorage) DeleteSession(userID uint, id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	session, ok := st.sessions[id]
+	if !ok || session.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	delete(st.sessions, id)
+	return nil
+}
+
+func (st *MemoryStorage) DeleteUserSessions(userID uint) ([]string, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.deleteUserSessions(userID), nil
+}
+
+// deleteUserSessions deletes the sessions of the user and returns their IDs.
+// The caller must hold the lock.
+func (st *MemoryStorage) deleteUserSessions(userID uint) []string {
+	ids := make([]string, 0)
+	for id, session := range st.sessions {
+		if session.UserID == userID {
+			delete(st.sessions, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"errors"
+	"go-users/storage/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// createUsers saves a user per username and returns their ids in creation
+// order.
+func createUsers(t *testing.T, st *MemoryStorage, usernames ...string) []uint {
+	t.Helper()
+	ids := make([]uint, 0, len(usernames))
+	for _, username := range usernames {
+		id, err := st.CreateUser(&models.User{Username: username, Email: username + "@example.com"})
+		if err != nil {
+			t.Fatalf("CreateUser(%v) = %v", username, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func userIDs(users []models.User) []uint {
+	ids := make([]uint, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+func equalIDs(a []uint, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMemoryStorageUniqueUsers(t *testing.T) {
+	st := NewMemoryStorage()
+	createUsers(t, st, "alice")
+
+	tests := []struct {
+		name string
+		user models.User
+		want error
+	}{
+		{name: "taken username", user: models.User{Username: "alice", Email: "other@example.com"}, want: ErrDuplicateUser},
+		{name: "taken email", user: models.User{Username: "other", Email: "alice@example.com"}, want: ErrDuplicateUser},
+		{name: "new user", user: models.User{Username: "bob", Email: "bob@example.com"}},
+	}
+	for _, tt := range tests {
+		if _, err := st.CreateUser(&tt.user); !errors.Is(err, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestMemoryStorageFollowConflicts(t *testing.T) {
+	st := NewMemoryStorage()
+	ids := createUsers(t, st, "alice", "bob")
+	alice, bob := ids[0], ids[1]
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{name: "follow", call: func() error { return st.Follow(alice, bob) }},
+		{name: "follow again", call: func() error { return st.Follow(alice, bob) }, want: ErrAlreadyFollowing},
+		{name: "follow themselves", call: func() error { return st.Follow(alice, alice) }, want: ErrSelfFollow},
+		{name: "follow a missing user", call: func() error { return st.Follow(alice, 1000) }, want: gorm.ErrRecordNotFound},
+		{name: "unfollow", call: func() error { return st.Unfollow(alice, bob) }},
+		{name: "unfollow again", call: func() error { return st.Unfollow(alice, bob) }, want: ErrNotFollowing},
+	}
+	for _, tt := range tests {
+		if err := tt.call(); !errors.Is(err, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	for _, id := range ids {
+		user, _ := st.GetUserByID(int(id))
+		if user.FollowersCount != 0 || user.FollowingCount != 0 {
+			t.Errorf("user %v has %v followers and %v followed after unfollowing", id, user.FollowersCount, user.FollowingCount)
+		}
+	}
+}
+
+func TestMemoryStorageFollowOrdering(t *testing.T) {
+	st := NewMemoryStorage()
+	ids := createUsers(t, st, "target", "a", "b", "c")
+	target, followers := ids[0], ids[1:]
+
+	base := time.Now()
+	for i, id := range followers {
+		if err := st.Follow(id, target); err != nil {
+			t.Fatalf("Follow() = %v", err)
+		}
+		st.follows[followKey{followerID: id, followeeID: target}] = base.Add(time.Duration(i) * time.Second)
+	}
+
+	tests := []struct {
+		name   string
+		limit  int
+		offset int
+		want   []uint
+	}{
+		{name: "most recent first", limit: 10, want: []uint{followers[2], followers[1], followers[0]}},
+		{name: "limited", limit: 2, want: []uint{followers[2], followers[1]}},
+		{name: "with offset", limit: 2, offset: 2, want: []uint{followers[0]}},
+		{name: "past the end", limit: 2, offset: 3, want: []uint{}},
+	}
+	for _, tt := range tests {
+		users, err := st.GetFollowers(target, tt.limit, tt.offset)
+		if err != nil {
+			t.Fatalf("%v: GetFollowers() = %v", tt.name, err)
+		}
+		if got := userIDs(users); !equalIDs(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	following, _ := st.GetFollowing(followers[0], 10, 0)
+	if got := userIDs(following); !equalIDs(got, []uint{target}) {
+		t.Errorf("GetFollowing() = %v, want %v", got, []uint{target})
+	}
+	user, _ := st.GetUserByID(int(target))
+	if user.FollowersCount != uint(len(followers)) {
+		t.Errorf("FollowersCount = %v, want %v", user.FollowersCount, len(followers))
+	}
+}
+
+func TestMemoryStorageSessions(t *testing.T) {
+	st := NewMemoryStorage()
+	now := time.Now()
+	session := func(id string, userID uint) *models.Session {
+		return &models.Session{ID: id, UserID: userID, RefreshTokenHash: "hash-" + id, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{name: "create", call: func() error { return st.CreateSession(session("a", 1)) }},
+		{name: "create a taken id", call: func() error { return st.CreateSession(session("a", 1)) }, want: gorm.ErrDuplicatedKey},
+		{name: "rotate", call: func() error {
+			return st.RotateSession("hash-a", &models.Session{ID: "a", RefreshTokenHash: "hash-a2", LastUsedAt: now, ExpiresAt: now.Add(time.Hour)})
+		}},
+		{name: "rotate a reused token", call: func() error {
+			return st.RotateSession("hash-a", &models.Session{ID: "a", RefreshTokenHash: "hash-a3", LastUsedAt: now, ExpiresAt: now.Add(time.Hour)})
+		}, want: ErrSessionRotated},
+		{name: "delete of another user", call: func() error { return st.DeleteSession(2, "a") }, want: gorm.ErrRecordNotFound},
+		{name: "delete", call: func() error { return st.DeleteSession(1, "a") }},
+		{name: "get deleted", call: func() error { _, err := st.GetSession("a"); return err }, want: gorm.ErrRecordNotFound},
+	}
+	for _, tt := range tests {
+		if err := tt.call(); !errors.Is(err, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
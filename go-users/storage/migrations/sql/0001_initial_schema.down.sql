@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS follows;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,25 @@
+-- The schema previously created by AutoMigrate. Every statement is guarded,
+-- so databases created that way are adopted as they are.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    username text UNIQUE,
+    display_name text NOT NULL DEFAULT '',
+    email text UNIQUE,
+    password text NOT NULL,
+    created_at timestamptz,
+    refresh_token text NOT NULL DEFAULT '',
+    followers_count bigint NOT NULL DEFAULT 0,
+    following_count bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_users_refresh_token ON users (refresh_token);
+
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id bigint NOT NULL,
+    followee_id bigint NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (follower_id, followee_id)
+);
+CREATE INDEX IF NOT EXISTS idx_follows_followee_id ON follows (followee_id);
+CREATE INDEX IF NOT EXISTS idx_follows_follower_id_created_at ON follows (follower_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_follows_followee_id_created_at ON follows (followee_id, created_at DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS refresh_token text NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_users_refresh_token ON users (refresh_token);
+
+DROP TABLE IF EXISTS sessions;
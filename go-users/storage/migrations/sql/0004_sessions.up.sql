@@ -0,0 +1,21 @@
+-- Refresh tokens move from the single users.refresh_token column to one row
+-- per signed in device. The old tokens are dropped, so everyone has to sign
+-- in again once their access token expires.
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id text PRIMARY KEY,
+    user_id bigint NOT NULL,
+    device_name text NOT NULL DEFAULT '',
+    ip text NOT NULL DEFAULT '',
+    user_agent text NOT NULL DEFAULT '',
+    refresh_token_hash text NOT NULL,
+    previous_token_hash text NOT NULL DEFAULT '',
+    rotated_at timestamptz,
+    created_at timestamptz,
+    last_used_at timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id_last_used_at ON sessions (user_id, last_used_at DESC);
+
+DROP INDEX IF EXISTS idx_users_refresh_token;
+ALTER TABLE users DROP COLUMN IF EXISTS refresh_token;
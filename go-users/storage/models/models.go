@@ -3,10 +3,64 @@ package models
 import "time"
 
 type User struct {
-	ID           uint      `gorm:"primaryKey"`
-	Username     string    `gorm:"unique"`
-	Email        string    `gorm:"unique"`
-	Password     string    `gorm:"not null"`
-	CreatedAt    time.Time `gorm:"autoCreateTime"`
-	RefreshToken string    `gorm:"not null;default:''"`
+	ID          uint      `gorm:"primaryKey"`
+	Username    string    `gorm:"unique"`
+	DisplayName string    `gorm:"not null;default:''"`
+	Email       string    `gorm:"unique"`
+	Password    string    `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+
+	// EmailVerifiedAt is set once the user proves they own Email.
+	EmailVerifiedAt *time.Time
+
+	FollowersCount uint `gorm:"not null;default:0"`
+	FollowingCount uint `gorm:"not null;default:0"`
+}
+
+// Follow is a directed edge of the follow graph: FollowerID follows FolloweeID.
+type Follow struct {
+	FollowerID uint      `gorm:"primaryKey;autoIncrement:false"`
+	FolloweeID uint      `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// PasswordReset is a one-time password reset token of a user. Only the
+// SHA-256 hash of the token is stored, the token itself is only e-mailed.
+type PasswordReset struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;unique"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// EmailVerification is a one-time token proving the ownership of the e-mail
+// address of a user. Like with password resets, only its hash is stored.
+type EmailVerification struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;unique"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Session is a signed in device of a user. Its refresh token is rotated on
+// every refresh and only the hash of the current one is stored, together with
+// the previous one so that a concurrent refresh is not mistaken for a stolen
+// token being reused. All the refresh tokens of a session form one family,
+// reusing any old one revokes the whole session.
+type Session struct {
+	ID                string `gorm:"primaryKey"`
+	UserID            uint   `gorm:"not null;index"`
+	DeviceName        string `gorm:"not null;default:''"`
+	IP                string `gorm:"not null;default:''"`
+	UserAgent         string `gorm:"not null;default:''"`
+	RefreshTokenHash  string `gorm:"not null"`
+	PreviousTokenHash string `gorm:"not null;default:''"`
+	RotatedAt         *time.Time
+	CreatedAt         time.Time `gorm:"autoCreateTime"`
+	LastUsedAt        time.Time `gorm:"not null"`
+	ExpiresAt         time.Time `gorm:"not null"`
 }
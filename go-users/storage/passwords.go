@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"errors"
+	"go-users/storage/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidResetToken is returned by ResetPassword for unknown, used and
+// expired tokens alike.
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+func (st *PostgreStorage) GetUserByEmail(email string) (*models.User, error) {
+	var user *models.User
+	res := st.db.First(&user, "email", email)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return user, nil
+}
+
+func (st *PostgreStorage) CreatePasswordReset(reset *models.PasswordReset) error {
+	return st.db.Create(reset).Error
+}
+
+// ResetPassword consumes the reset token with the given hash, replaces the
+// password of its user and revokes all the user's sessions, which signs them
+// out everywhere. The other pending tokens of the user are consumed too. The
+// IDs of the revoked sessions are returned with the user.
+func (st *PostgreStorage) ResetPassword(tokenHash string, passwordHash string, now time.Time) (*models.User, []string, error) {
+	var user models.User
+	var sessionIDs []string
+	err := st.db.Transaction(func(tx *gorm.DB) error {
+		var reset models.PasswordReset
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			First(&reset).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.User{}).Where("id = ?", reset.UserID).Update("password", passwordHash).Error
+		if err != nil {
+			return err
+		}
+
+		sessionIDs, err = deleteUserSessions(tx, reset.UserID)
+		if err != nil {
+			return err
+		}
+
+		return tx.First(&user, reset.UserID).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &user, sessionIDs, nil
+}
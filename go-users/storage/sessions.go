@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"errors"
+	"go-users/storage/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSessionRotated is returned by RotateSession when the refresh token of the
+// session was rotated by another refresh in the meantime.
+var ErrSessionRotated = errors.New("session was already rotated")
+
+// CreateSession saves a new session of a user and drops the user's expired
+// ones, which are never cleaned up otherwise.
+func (st *PostgreStorage) CreateSession(session *models.Session) error {
+	return st.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND expires_at <= ?", session.UserID, session.LastUsedAt).
+			Delete(&models.Session{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(session).Error
+	})
+}
+
+func (st *PostgreStorage) GetSession(id string) (*models.Session, error) {
+	var session *models.Session
+	res := st.db.First(&session, "id = ?", id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return session, nil
+}
+
+// GetUserSessions returns the unexpired sessions of the user, the most
+// recently used first.
+func (st *PostgreStorage) GetUserSessions(userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	res := st.db.Where("user_id = ? AND expires_at > ?", userID, now).
+		Order("last_used_at desc").
+		Find(&sessions)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return sessions, nil
+}
+
+// RotateSession replaces the refresh token of the session, provided it is
+// still the one hashed to tokenHash, with session.RefreshTokenHash and saves
+// the session's IP, user agent, last use and expiry. The replaced hash is kept
+// as the previous one.
+func (st *PostgreStorage) RotateSession(tokenHash string, session *models.Session) error {
+	res := st.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", session.ID, tokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  session.RefreshTokenHash,
+			"previous_token_hash": tokenHash,
+			"rotated_at":          session.LastUsedAt,
+			"ip":                  session.IP,
+			"user_agent":          session.UserAgent,
+			"last_used_at":        session.LastUsedAt,
+			"expires_at":          session.ExpiresAt,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSessionRotated
+	}
+	return nil
+}
+
+// DeleteSession revokes one session of the user. gorm.ErrRecordNotFound is
+// returned if the user has no such session.
+func (st *PostgreStorage) DeleteSession(userID uint, id string) error {
+	res := st.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Session{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteUserSessions revokes every session of the user and returns their IDs.
+func (st *PostgreStorage) DeleteUserSessions(userID uint) ([]string, error) {
+	return deleteUserSessions(st.db, userID)
+}
+
+func deleteUserSessions(tx *gorm.DB, userID uint) ([]string, error) {
+	var sessions []models.Session
+	res := tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("user_id = ?", userID).
+		Delete(&sessions)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	ids := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+	return ids, nil
+}
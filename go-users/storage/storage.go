@@ -2,7 +2,9 @@ package storage
 
 import (
 	"errors"
+	"go-users/storage/migrations"
 	"go-users/storage/models"
+	"os"
 	"time"
 
 	"go.uber.org/zap"
@@ -10,20 +12,79 @@ import (
 	"gorm.io/gorm"
 )
 
-type Storage struct {
+// ErrDuplicateUser is returned by CreateUser when the username or the email
+// is already taken.
+var ErrDuplicateUser = errors.New("username or email is already taken")
+
+type Storage interface {
+	CreateUser(user *models.User) (uint, error)
+	GetUserByUsername(username string) (*models.User, error)
+	GetUserByID(id int) (*models.User, error)
+	GetUsersByIDs(ids []uint) ([]models.User, error)
+	UpdateDisplayName(id uint, displayName string) error
+
+	CreateSession(session *models.Session) error
+	GetSession(id string) (*models.Session, error)
+	GetUserSessions(userID uint, now time.Time) ([]models.Session, error)
+	RotateSession(tokenHash string, session *models.Session) error
+	DeleteSession(userID uint, id string) error
+	DeleteUserSessions(userID uint) ([]string, error)
+
+	GetUserByEmail(email string) (*models.User, error)
+	CreatePasswordReset(reset *models.PasswordReset) error
+	ResetPassword(tokenHash string, passwordHash string, now time.Time) (*models.User, []string, error)
+
+	CreateEmailVerification(verification *models.EmailVerification) error
+	GetLatestEmailVerification(userID uint) (*models.EmailVerification, error)
+	VerifyEmail(tokenHash string, now time.Time) (*models.User, error)
+
+	Follow(followerID uint, followeeID uint) error
+	Unfollow(followerID uint, followeeID uint) error
+	GetFollowers(userID uint, limit int, offset int) ([]models.User, error)
+	GetFollowing(userID uint, limit int, offset int) ([]models.User, error)
+	GetFollowerIDs(userID uint) ([]uint, error)
+	GetFollowedUsers(userID uint) ([]FollowedUser, error)
+}
+
+// New creates the storage selected by the STORAGE_BACKEND ENV: "postgres"
+// (the default, connecting to DB_ADDR) or "memory" for an in-process storage,
+// which needs no database but loses its data on restart.
+func New(logger *zap.Logger) Storage {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "postgres":
+		st := &PostgreStorage{Logger: logger}
+		st.Init(os.Getenv("DB_ADDR"))
+		return st
+	case "memory":
+		logger.Info("Using in-memory storage")
+		return NewMemoryStorage()
+	default:
+		logger.Fatal("Unknown STORAGE_BACKEND", zap.String("backend", backend))
+		return nil
+	}
+}
+
+type PostgreStorage struct {
 	db     *gorm.DB
 	Logger *zap.Logger
 }
 
-// Init initializes the PostgreStorage and connects to the given database
-func (st *Storage) Init(dsn string) {
+// Init initializes the PostgreStorage, connects to the given database and
+// applies the pending migrations
+func (st *PostgreStorage) Init(dsn string) {
+	st.Connect(dsn)
+	st.Migrate()
+}
+
+// Connect connects to the given database
+func (st *PostgreStorage) Connect(dsn string) {
 	st.Logger.Debug("Conncting to the database...", zap.String("dsn: ", dsn))
 
 	var db *gorm.DB
 	var err error
 
 	for i := 0; i < 5; i++ {
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 		if err == nil {
 			break
 		}
@@ -35,26 +96,50 @@ func (st *Storage) Init(dsn string) {
 		panic("Failed to connect to the database")
 	}
 
-	err = db.AutoMigrate(&models.User{})
+	st.Logger.Info("Successfully connected to the database")
+
+	st.db = db
+}
+
+// migrationsLockID is the advisory lock of the go-users migrations, distinct
+// from the go-posts one.
+const migrationsLockID = 7_380_235_002
+
+// Migrator returns the migrator of the connected database
+func (st *PostgreStorage) Migrator() (*migrations.Migrator, error) {
+	return migrations.New(st.db, migrationsLockID)
+}
+
+// Migrate applies the pending migrations
+func (st *PostgreStorage) Migrate() {
+	migrator, err := st.Migrator()
 	if err != nil {
-		st.Logger.Error("Error occured while migrating models", zap.String("Erorr: ", err.Error()))
+		st.Logger.Error("Error occured while loading migrations", zap.String("Erorr: ", err.Error()))
 		panic(err)
 	}
 
-	st.Logger.Info("Successfully connected to the database")
-
-	st.db = db
+	applied, err := migrator.Up()
+	for _, migration := range applied {
+		st.Logger.Info("Applied migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+	}
+	if err != nil {
+		st.Logger.Error("Error occured while migrating the database", zap.String("Erorr: ", err.Error()))
+		panic(err)
+	}
 }
 
-func (st *Storage) CreateUser(user *models.User) (uint, error) {
+func (st *PostgreStorage) CreateUser(user *models.User) (uint, error) {
 	res := st.db.Create(user)
+	if errors.Is(res.Error, gorm.ErrDuplicatedKey) {
+		return 0, ErrDuplicateUser
+	}
 	if res.Error != nil {
 		return 0, res.Error
 	}
 	return user.ID, nil
 }
 
-func (st *Storage) GetUserByUsername(username string) (*models.User, error) {
+func (st *PostgreStorage) GetUserByUsername(username string) (*models.User, error) {
 	var user *models.User
 	res := st.db.First(&user, "username", username)
 	if res.Error != nil {
@@ -63,7 +148,7 @@ func (st *Storage) GetUserByUsername(username string) (*models.User, error) {
 	return user, nil
 }
 
-func (st *Storage) GetUserByID(id int) (*models.User, error) {
+func (st *PostgreStorage) GetUserByID(id int) (*models.User, error) {
 	var user *models.User
 	res := st.db.First(&user, "id", id)
 	if res.Error != nil {
@@ -73,31 +158,25 @@ func (st *Storage) GetUserByID(id int) (*models.User, error) {
 	return user, nil
 }
 
-func (st *Storage) UpdateUserRefreshToken(username string, new_token string) error {
-	var user *models.User
-	res := st.db.First(&user, "username", username)
-	if res.Error != nil {
-		return res.Error
+// GetUsersByIDs returns the existing users among the given ids, in no
+// particular order.
+func (st *PostgreStorage) GetUsersByIDs(ids []uint) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
 	}
 
-	res = st.db.Model(&user).Update("RefreshToken", new_token)
+	res := st.db.Where("id IN ?", ids).Find(&users)
 	if res.Error != nil {
-		return res.Error
+		return nil, res.Error
 	}
-
-	return nil
+	return users, nil
 }
 
-func (st *Storage) GetUserByRefreshToken(token string) (*models.User, error) {
-	if token == "" {
-		return nil, errors.New("invalid token")
-	}
-
-	var user *models.User
-	res := st.db.First(&user, "refresh_token", token)
+func (st *PostgreStorage) UpdateDisplayName(id uint, displayName string) error {
+	res := st.db.Model(&models.User{}).Where("id = ?", id).Update("display_name", displayName)
 	if res.Error != nil {
-		return nil, res.Error
+		return res.Error
 	}
-
-	return user, nil
+	return nil
 }
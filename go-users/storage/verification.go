@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"errors"
+	"go-users/storage/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidVerificationToken is returned by VerifyEmail for unknown, used and
+// expired tokens alike.
+var ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+func (st *PostgreStorage) CreateEmailVerification(verification *models.EmailVerification) error {
+	return st.db.Create(verification).Error
+}
+
+// GetLatestEmailVerification returns the last verification token issued to
+// the user, which is what resending is throttled by.
+func (st *PostgreStorage) GetLatestEmailVerification(userID uint) (*models.EmailVerification, error) {
+	var verification *models.EmailVerification
+	res := st.db.Where("user_id = ?", userID).Order("created_at desc").First(&verification)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return verification, nil
+}
+
+// VerifyEmail consumes the verification token with the given hash, together
+// with the other pending tokens of its user, and marks the user's e-mail
+// address as verified.
+func (st *PostgreStorage) VerifyEmail(tokenHash string, now time.Time) (*models.User, error) {
+	var user models.User
+	err := st.db.Transaction(func(tx *gorm.DB) error {
+		var verification models.EmailVerification
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			First(&verification).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.EmailVerification{}).
+			Where("user_id = ? AND used_at IS NULL", verification.UserID).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.User{}).
+			Where("id = ? AND email_verified_at IS NULL", verification.UserID).
+			Update("email_verified_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.First(&user, verification.UserID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
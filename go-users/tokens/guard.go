@@ -2,12 +2,9 @@ package tokens
 
 import (
 	"errors"
-	"fmt"
 	"go-users/storage"
 	"strconv"
 	"time"
-
-	"github.com/golang-jwt/jwt"
 )
 
 type ValidationResults struct {
@@ -15,9 +12,16 @@ type ValidationResults struct {
 	Refresh_Token string
 	User_id       int
 	Username      string
+	SessionID     string
+	// TokenID is the jti of the presented access token, it is empty when the
+	// caller was authenticated by their refresh token.
+	TokenID string
 }
 
-func ValidateUser(storage *storage.Storage, tokenizer Tokenizer, access_token string, refresh_token string) (*ValidationResults, error) {
+// ValidateUser authenticates the caller by their access token or, once it has
+// expired, by the refresh token of their session, which is then rotated. New
+// tokens are returned in the results only when they have to be replaced.
+func ValidateUser(store storage.Storage, tokenizer Tokenizer, access_token string, refresh_token string, client Client) (*ValidationResults, error) {
 	accessClaims, err := tokenizer.ParseAccessToken(access_token)
 	if err == nil && accessClaims != nil {
 		// Access token is valid
@@ -27,44 +31,76 @@ func ValidateUser(storage *storage.Storage, tokenizer Tokenizer, access_token st
 			Refresh_Token: "",
 			User_id:       user_id,
 			Username:      accessClaims.Username,
+			SessionID:     accessClaims.SessionID,
+			TokenID:       accessClaims.StandardClaims.Id,
 		}
 
 		return res, nil
 	}
 
 	// Access token is invalid
-	_, err = tokenizer.ParseRefreshToken(refresh_token)
-	if err != nil {
+	refreshClaims, err := tokenizer.ParseRefreshToken(refresh_token)
+	if err != nil || refreshClaims.SessionID == "" {
 		//Refresh token is invalid
 		return nil, errors.New("Invalid tokens")
 	}
 
-	user, err := storage.GetUserByRefreshToken(refresh_token)
+	session, err := store.GetSession(refreshClaims.SessionID)
 	if err != nil {
 		return nil, errors.New("Invalid tokens")
 	}
 
-	newRefreshToken, err := tokenizer.NewRefreshToken(jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour * 24 * 7).Unix()})
+	now := time.Now()
+	tokenHash := hashToken(refresh_token)
+	rotate := true
+	switch {
+	case tokenHash == session.RefreshTokenHash:
+	case tokenHash == session.PreviousTokenHash && session.RotatedAt != nil && now.Sub(*session.RotatedAt) < refreshReuseGrace():
+		// a parallel request has just rotated the token, its response
+		// carries the new one
+		rotate = false
+	default:
+		store.DeleteSession(session.UserID, session.ID)
+		tokenizer.RevokeAccessTokens(session.ID)
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := store.GetUserByID(int(session.UserID))
 	if err != nil {
-		return nil, errors.New("Internal server error")
+		return nil, errors.New("Invalid tokens")
 	}
 
-	storage.UpdateUserRefreshToken(user.Username, newRefreshToken)
+	res := &ValidationResults{
+		User_id:   int(user.ID),
+		Username:  user.Username,
+		SessionID: session.ID,
+	}
 
-	newAccessToken, err := tokenizer.NewAccessToken(UserClaims{
-		Id:       fmt.Sprint(user.ID),
-		Username: user.Username,
-		Email:    user.Email,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
-		},
-	})
+	if rotate {
+		newRefreshToken, err := newRefreshToken(tokenizer, session.ID, now)
+		if err != nil {
+			return nil, errors.New("Internal server error")
+		}
 
-	res := &ValidationResults{
-		User_id:       int(user.ID),
-		Username:      user.Username,
-		Access_Token:  newAccessToken,
-		Refresh_Token: newRefreshToken,
+		session.RefreshTokenHash = hashToken(newRefreshToken)
+		session.IP = client.IP
+		session.UserAgent = client.UserAgent
+		session.LastUsedAt = now
+		session.ExpiresAt = now.Add(RefreshTokenTTL)
+		err = store.RotateSession(tokenHash, session)
+		switch {
+		case errors.Is(err, storage.ErrSessionRotated):
+			// lost the race against a parallel request, like above
+		case err != nil:
+			return nil, errors.New("Internal server error")
+		default:
+			res.Refresh_Token = newRefreshToken
+		}
+	}
+
+	res.Access_Token, err = newAccessToken(tokenizer, user, session.ID, now)
+	if err != nil {
+		return nil, errors.New("Internal server error")
 	}
 
 	return res, nil
@@ -0,0 +1,258 @@
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+// minRSABits is the smallest RSA key accepted for signing tokens.
+const minRSABits = 2048
+
+// Key is a key the tokens are signed or verified with, its ID is the kid
+// header of the tokens. Keys without a private part only verify tokens.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+}
+
+// KeySet holds the active keys. New tokens are signed with Signing, the
+// others are kept to verify the tokens signed before a rotation.
+type KeySet struct {
+	Signing *Key
+	keys    map[string]*Key
+}
+
+// LoadKeys loads the key set from the PEM files in TOKEN_KEYS_DIR, each named
+// after its kid, e.g. "2024-05.pem". A file holds either a private key, RSA
+// or Ed25519 as written by `openssl genpkey -algorithm ed25519`, or only a
+// public key of a retired one. New tokens are signed with the key named by
+// TOKEN_SIGNING_KEY, by default the last private key in the order of the
+// kids.
+//
+// To rotate, add the new key to every replica first, then make it the signing
+// key, and drop the old one once the tokens signed with it have expired.
+//
+// Tokens without a kid header, like the HS256 ones issued before the key
+// sets, are rejected, so every user has to sign in again once this is
+// deployed.
+//
+// LoadKeys fails without TOKEN_KEYS_DIR, unless TOKEN_DEV_KEYS is "true": then
+// an Ed25519 key is generated on start, which is fine for a single local
+// replica only, as its tokens do not survive a restart.
+func LoadKeys(logger *zap.Logger) (*KeySet, error) {
+	dir := os.Getenv("TOKEN_KEYS_DIR")
+	if dir == "" {
+		if os.Getenv("TOKEN_DEV_KEYS") != "true" {
+			return nil, errors.New("TOKEN_KEYS_DIR is not set, set TOKEN_DEV_KEYS=true to sign with a temporary key")
+		}
+		logger.Warn("TOKEN_KEYS_DIR is not set, signing tokens with a temporary key")
+		return GenerateKeySet()
+	}
+	return LoadKeySet(dir, os.Getenv("TOKEN_SIGNING_KEY"))
+}
+
+// LoadKeySet loads the keys in dir, see LoadKeys.
+func LoadKeySet(dir string, signingKeyID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	set := &KeySet{keys: make(map[string]*Key, len(paths))}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := parseKey(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("key %v: %w", path, err)
+		}
+
+		set.keys[id] = key
+		if key.Private != nil && signingKeyID == "" {
+			set.Signing = key
+		}
+	}
+
+	if signingKeyID != "" {
+		set.Signing = set.keys[signingKeyID]
+		if set.Signing == nil || set.Signing.Private == nil {
+			return nil, fmt.Errorf("no private key %q in %v", signingKeyID, dir)
+		}
+	}
+	if set.Signing == nil {
+		return nil, fmt.Errorf("no private key in %v", dir)
+	}
+
+	return set, nil
+}
+
+// GenerateKeySet returns a key set of a single new Ed25519 key.
+func GenerateKeySet() (*KeySet, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: id, Method: jwt.SigningMethodEdDSA, Private: private, Public: public}
+	return &KeySet{Signing: key, keys: map[string]*Key{id: key}}, nil
+}
+
+// GenerateKeyFile writes a new Ed25519 private key to path in the PEM format
+// LoadKeySet reads. It never overwrites an existing file.
+func GenerateKeyFile(path string) error {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func parseKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key shorter than %v bits", minRSABits)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, Private: key, Public: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, Public: key}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, Private: key, Public: key.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, Public: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
+
+// sign signs the claims with the signing key.
+func (s *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.Signing.Method, claims)
+	token.Header["kid"] = s.Signing.ID
+	return token.SignedString(s.Signing.Private)
+}
+
+// verificationKey is the jwt.Keyfunc of the set. The key is looked up by the
+// kid header and must have been made for the algorithm of the token.
+func (s *KeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Method.Alg())
+	}
+	return key.Public, nil
+}
+
+// JWK is a public key in the JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 (RFC 8037)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is the JSON Web Key Set of the active keys.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public parts of all the active keys, so that other
+// services can verify the tokens on their own.
+func (s *KeySet) JWKS() JWKSet {
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JWKSet{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		key := s.keys[id]
+		jwk := JWK{Kid: id, Use: "sig", Alg: key.Method.Alg()}
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
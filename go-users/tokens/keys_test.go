@@ -0,0 +1,285 @@
+package tokens
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+func encodePEM(t *testing.T, blockType string, der []byte) []byte {
+	t.Helper()
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func marshalPKCS8(t *testing.T, key interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func marshalPKIX(t *testing.T, key interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func generateRSA(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func generateEd25519(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return public, private
+}
+
+// writeKeys writes every PEM file of files to a new directory.
+func writeKeys(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestParseKey(t *testing.T) {
+	rsaKey := generateRSA(t, 2048)
+	shortRSAKey := generateRSA(t, 1024)
+	edPublic, edPrivate := generateEd25519(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		method      jwt.SigningMethod
+		private     bool
+		errContains string
+	}{
+		{name: "RSA PKCS8", data: encodePEM(t, "PRIVATE KEY", marshalPKCS8(t, rsaKey)), method: jwt.SigningMethodRS256, private: true},
+		{name: "RSA PKCS1", data: encodePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), method: jwt.SigningMethodRS256, private: true},
+		{name: "RSA public", data: encodePEM(t, "PUBLIC KEY", marshalPKIX(t, &rsaKey.PublicKey)), method: jwt.SigningMethodRS256},
+		{name: "RSA PKCS1 public", data: encodePEM(t, "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)), method: jwt.SigningMethodRS256},
+		{name: "Ed25519", data: encodePEM(t, "PRIVATE KEY", marshalPKCS8(t, edPrivate)), method: jwt.SigningMethodEdDSA, private: true},
+		{name: "Ed25519 public", data: encodePEM(t, "PUBLIC KEY", marshalPKIX(t, edPublic)), method: jwt.SigningMethodEdDSA},
+		{name: "short RSA", data: encodePEM(t, "PRIVATE KEY", marshalPKCS8(t, shortRSAKey)), errContains: "shorter than"},
+		{name: "unsupported key type", data: encodePEM(t, "PRIVATE KEY", marshalPKCS8(t, ecKey)), errContains: "unsupported key type"},
+		{name: "unsupported PEM block", data: encodePEM(t, "CERTIFICATE", []byte{1}), errContains: "unsupported PEM block"},
+		{name: "not PEM", data: []byte("secret"), errContains: "no PEM data"},
+		{name: "corrupt key", data: encodePEM(t, "PRIVATE KEY", []byte{1, 2, 3}), errContains: "asn1"},
+	}
+	for _, tt := range tests {
+		key, err := parseKey("kid", tt.data)
+		if tt.errContains != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("%v: got %v, want an error containing %q", tt.name, err, tt.errContains)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+			continue
+		}
+		if key.ID != "kid" || key.Method != tt.method || (key.Private != nil) != tt.private || key.Public == nil {
+			t.Errorf("%v: got %+v", tt.name, key)
+		}
+	}
+}
+
+func TestLoadKeySetSigningKey(t *testing.T) {
+	_, first := generateEd25519(t)
+	_, second := generateEd25519(t)
+	retiredPublic, _ := generateEd25519(t)
+	dir := writeKeys(t, map[string][]byte{
+		"2024-01.pem": encodePEM(t, "PRIVATE KEY", marshalPKCS8(t, first)),
+		"2024-02.pem": encodePEM(t, "PRIVATE KEY", marshalPKCS8(t, second)),
+		"2024-03.pem": encodePEM(t, "PUBLIC KEY", marshalPKIX(t, retiredPublic)),
+		"notes.txt":   []byte("ignored"),
+	})
+
+	tests := []struct {
+		name      string
+		dir       string
+		signingID string
+		want      string
+		wantErr   bool
+	}{
+		{name: "last private key by default", dir: dir, want: "2024-02"},
+		{name: "chosen key", dir: dir, signingID: "2024-01", want: "2024-01"},
+		{name: "chosen public key", dir: dir, signingID: "2024-03", wantErr: true},
+		{name: "chosen missing key", dir: dir, signingID: "2023-12", wantErr: true},
+		{name: "no keys", dir: t.TempDir(), wantErr: true},
+		{name: "only public keys", dir: writeKeys(t, map[string][]byte{"old.pem": encodePEM(t, "PUBLIC KEY", marshalPKIX(t, retiredPublic))}), wantErr: true},
+		{name: "invalid key", dir: writeKeys(t, map[string][]byte{"bad.pem": []byte("secret")}), wantErr: true},
+	}
+	for _, tt := range tests {
+		set, err := LoadKeySet(tt.dir, tt.signingID)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%v: signing with %v, want an error", tt.name, set.Signing.ID)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+			continue
+		}
+		if set.Signing.ID != tt.want {
+			t.Errorf("%v: signing with %v, want %v", tt.name, set.Signing.ID, tt.want)
+		}
+		if len(set.keys) != 3 {
+			t.Errorf("%v: loaded %v keys, want 3", tt.name, len(set.keys))
+		}
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	logger := zap.NewNop()
+
+	t.Setenv("TOKEN_KEYS_DIR", "")
+	t.Setenv("TOKEN_DEV_KEYS", "")
+	if _, err := LoadKeys(logger); err == nil {
+		t.Error("LoadKeys() without TOKEN_KEYS_DIR succeeded")
+	}
+
+	t.Setenv("TOKEN_DEV_KEYS", "true")
+	if set, err := LoadKeys(logger); err != nil || set.Signing == nil {
+		t.Errorf("LoadKeys() with TOKEN_DEV_KEYS = %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := GenerateKeyFile(filepath.Join(dir, "2024-05.pem")); err != nil {
+		t.Fatalf("GenerateKeyFile() = %v", err)
+	}
+	if err := GenerateKeyFile(filepath.Join(dir, "2024-05.pem")); err == nil {
+		t.Error("GenerateKeyFile() overwrote a key")
+	}
+	t.Setenv("TOKEN_KEYS_DIR", dir)
+	t.Setenv("TOKEN_DEV_KEYS", "")
+	set, err := LoadKeys(logger)
+	if err != nil {
+		t.Fatalf("LoadKeys() = %v", err)
+	}
+	if set.Signing.ID != "2024-05" || set.Signing.Method != jwt.SigningMethodEdDSA {
+		t.Errorf("signing with %v %v, want the generated key", set.Signing.ID, set.Signing.Method.Alg())
+	}
+}
+
+func TestKeySetVerification(t *testing.T) {
+	_, old := generateEd25519(t)
+	_, current := generateEd25519(t)
+	oldDir := writeKeys(t, map[string][]byte{"old.pem": encodePEM(t, "PRIVATE KEY", marshalPKCS8(t, old))})
+	bothDir := writeKeys(t, map[string][]byte{
+		"old.pem":     encodePEM(t, "PRIVATE KEY", marshalPKCS8(t, old)),
+		"current.pem": encodePEM(t, "PRIVATE KEY", marshalPKCS8(t, current)),
+	})
+	oldSet, err := LoadKeySet(oldDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := LoadKeySet(bothDir, "current")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(s *KeySet) string {
+		token, err := s.sign(jwt.StandardClaims{Subject: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	hs256, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{Subject: "1"}).SignedString([]byte("secret"))
+	unknownKid := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.StandardClaims{Subject: "1"})
+	unknownKid.Header["kid"] = "missing"
+	unknown, _ := unknownKid.SignedString(current)
+	wrongAlg := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{Subject: "1"})
+	wrongAlg.Header["kid"] = "current"
+	confused, _ := wrongAlg.SignedString([]byte("secret"))
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "signed with the signing key", token: sign(set), valid: true},
+		{name: "signed before a rotation", token: sign(oldSet), valid: true},
+		{name: "HS256 without kid", token: hs256},
+		{name: "unknown kid", token: unknown},
+		{name: "algorithm of another key type", token: confused},
+	}
+	for _, tt := range tests {
+		_, err := jwt.Parse(tt.token, set.verificationKey)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("%v: valid = %v, want %v (%v)", tt.name, valid, tt.valid, err)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey := generateRSA(t, 2048)
+	edPublic, _ := generateEd25519(t)
+	dir := writeKeys(t, map[string][]byte{
+		"b-rsa.pem": encodePEM(t, "PRIVATE KEY", marshalPKCS8(t, rsaKey)),
+		"a-ed.pem":  encodePEM(t, "PUBLIC KEY", marshalPKIX(t, edPublic)),
+	})
+	set, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := set.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS() has %v keys, want 2", len(jwks.Keys))
+	}
+
+	ed := jwks.Keys[0]
+	if ed.Kid != "a-ed" || ed.Kty != "OKP" || ed.Crv != "Ed25519" || ed.Alg != "EdDSA" || ed.Use != "sig" {
+		t.Errorf("Ed25519 JWK = %+v", ed)
+	}
+	if x, _ := base64.RawURLEncoding.DecodeString(ed.X); !edPublic.Equal(ed25519.PublicKey(x)) {
+		t.Errorf("Ed25519 JWK x = %v, want the public key", ed.X)
+	}
+
+	rs := jwks.Keys[1]
+	if rs.Kid != "b-rsa" || rs.Kty != "RSA" || rs.Alg != "RS256" || rs.Use != "sig" || rs.X != "" {
+		t.Errorf("RSA JWK = %+v", rs)
+	}
+	n, _ := base64.RawURLEncoding.DecodeString(rs.N)
+	e, _ := base64.RawURLEncoding.DecodeString(rs.E)
+	if new(big.Int).SetBytes(n).Cmp(rsaKey.N) != 0 || int(new(big.Int).SetBytes(e).Int64()) != rsaKey.E {
+		t.Errorf("RSA JWK n, e = %v, %v, want the public key", rs.N, rs.E)
+	}
+}
@@ -0,0 +1,133 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-users/storage"
+	"go-users/storage/models"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	AccessTokenTTL  = time.Hour * 24
+	RefreshTokenTTL = time.Hour * 24 * 7
+)
+
+// ErrRefreshTokenReused is returned by ValidateUser when an already rotated
+// refresh token is presented again. Either the client or whoever stole the
+// token used it after the other, so the session they share is revoked.
+var ErrRefreshTokenReused = errors.New("Refresh token reused, the session has been revoked")
+
+// Client is the device a request comes from, it is recorded on the session.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// StartSession signs the user in on a new device and returns the tokens of
+// the new session.
+func StartSession(store storage.Storage, tokenizer Tokenizer, user *models.User, client Client, deviceName string) (*ValidationResults, error) {
+	sessionID, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refreshToken, err := newRefreshToken(tokenizer, sessionID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		ID:               sessionID,
+		UserID:           user.ID,
+		DeviceName:       deviceName,
+		IP:               client.IP,
+		UserAgent:        client.UserAgent,
+		RefreshTokenHash: hashToken(refreshToken),
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(RefreshTokenTTL),
+	}
+	if err := store.CreateSession(session); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := newAccessToken(tokenizer, user, sessionID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ValidationResults{
+		Access_Token:  accessToken,
+		Refresh_Token: refreshToken,
+		User_id:       int(user.ID),
+		Username:      user.Username,
+		SessionID:     sessionID,
+	}, nil
+}
+
+// refreshReuseGrace is how long the previous refresh token of a session is
+// still accepted after a rotation. Requests sent in parallel with the same
+// expired access token all try to refresh, only the first one rotates and the
+// others must not be taken for a reuse. Set by REFRESH_REUSE_GRACE, 30s by
+// default.
+func refreshReuseGrace() time.Duration {
+	value, err := time.ParseDuration(os.Getenv("REFRESH_REUSE_GRACE"))
+	if err != nil || value < 0 {
+		return time.Second * 30
+	}
+	return value
+}
+
+func newAccessToken(tokenizer Tokenizer, user *models.User, sessionID string, now time.Time) (string, error) {
+	tokenID, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	return tokenizer.NewAccessToken(UserClaims{
+		Id:        fmt.Sprint(user.ID),
+		Username:  user.Username,
+		Email:     user.Email,
+		SessionID: sessionID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(AccessTokenTTL).Unix(),
+		},
+	})
+}
+
+func newRefreshToken(tokenizer Tokenizer, sessionID string, now time.Time) (string, error) {
+	tokenID, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	return tokenizer.NewRefreshToken(RefreshClaims{
+		SessionID: sessionID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID,
+			ExpiresAt: now.Add(RefreshTokenTTL).Unix(),
+		},
+	})
+}
+
+func randomID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
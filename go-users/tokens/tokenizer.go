@@ -1,45 +1,64 @@
 package tokens
 
 import (
+	"context"
 	"errors"
-	"os"
+	"go-users/denylist"
 
 	"github.com/golang-jwt/jwt"
 	"go.uber.org/zap"
 )
 
+// ErrRevokedToken is returned by ParseAccessToken for tokens revoked before
+// their expiry.
+var ErrRevokedToken = errors.New("access token has been revoked")
+
 type Tokenizer interface {
 	NewAccessToken(UserClaims) (string, error)
-	NewRefreshToken(jwt.StandardClaims) (string, error)
+	NewRefreshToken(RefreshClaims) (string, error)
 	ParseAccessToken(string) (*UserClaims, error)
-	ParseRefreshToken(string) (*jwt.StandardClaims, error)
+	ParseRefreshToken(string) (*RefreshClaims, error)
+	// RevokeAccessTokens revokes the access tokens with the given jti or
+	// session ID, none of them lives longer than AccessTokenTTL.
+	RevokeAccessTokens(ids ...string) error
+	// JWKS returns the public keys the tokens can be verified with.
+	JWKS() JWKSet
 }
 
 type UserClaims struct {
-	Id       string `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	Id        string `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid"`
+	jwt.StandardClaims
+}
+
+// RefreshClaims name the session a refresh token belongs to. The random
+// StandardClaims.Id keeps two tokens of the same session distinct even when
+// they are issued within the same second.
+type RefreshClaims struct {
+	SessionID string `json:"sid"`
 	jwt.StandardClaims
 }
 
+// JwtTokenizer signs the tokens with the signing key of Keys and puts its
+// kid into their header.
 type JwtTokenizer struct {
-	Logger *zap.Logger
+	Logger   *zap.Logger
+	Denylist denylist.Denylist
+	Keys     *KeySet
 }
 
 func (j *JwtTokenizer) NewAccessToken(userClaims UserClaims) (string, error) {
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, userClaims)
-	return accessToken.SignedString([]byte(os.Getenv("TOKEN_SECRET")))
+	return j.Keys.sign(userClaims)
 }
 
-func (j *JwtTokenizer) NewRefreshToken(claims jwt.StandardClaims) (string, error) {
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return refreshToken.SignedString([]byte(os.Getenv("TOKEN_SECRET")))
+func (j *JwtTokenizer) NewRefreshToken(claims RefreshClaims) (string, error) {
+	return j.Keys.sign(claims)
 }
 
 func (j *JwtTokenizer) ParseAccessToken(accessToken string) (*UserClaims, error) {
-	parsedAccessToken, err := jwt.ParseWithClaims(accessToken, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("TOKEN_SECRET")), nil
-	})
+	parsedAccessToken, err := jwt.ParseWithClaims(accessToken, &UserClaims{}, j.Keys.verificationKey)
 
 	if err != nil {
 		return nil, err
@@ -49,13 +68,30 @@ func (j *JwtTokenizer) ParseAccessToken(accessToken string) (*UserClaims, error)
 		return nil, errors.New("invalid access token provided")
 	}
 
-	return parsedAccessToken.Claims.(*UserClaims), nil
+	claims := parsedAccessToken.Claims.(*UserClaims)
+	var ids []string
+	for _, id := range []string{claims.StandardClaims.Id, claims.SessionID} {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	// without the denylist a revoked token could be used until it expires,
+	// so it is rejected and the caller falls back to the refresh token
+	revoked, err := j.Denylist.Contains(context.Background(), ids...)
+	if err != nil {
+		j.Logger.Error("Error occured while checking the denylist", zap.String("Error: ", err.Error()))
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrRevokedToken
+	}
+
+	return claims, nil
 }
 
-func (j *JwtTokenizer) ParseRefreshToken(refreshToken string) (*jwt.StandardClaims, error) {
-	parsedRefreshToken, err := jwt.ParseWithClaims(refreshToken, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("TOKEN_SECRET")), nil
-	})
+func (j *JwtTokenizer) ParseRefreshToken(refreshToken string) (*RefreshClaims, error) {
+	parsedRefreshToken, err := jwt.ParseWithClaims(refreshToken, &RefreshClaims{}, j.Keys.verificationKey)
 
 	if err != nil {
 		return nil, err
@@ -65,5 +101,21 @@ func (j *JwtTokenizer) ParseRefreshToken(refreshToken string) (*jwt.StandardClai
 		return nil, errors.New("invalid refresh token provided")
 	}
 
-	return parsedRefreshToken.Claims.(*jwt.StandardClaims), nil
+	return parsedRefreshToken.Claims.(*RefreshClaims), nil
+}
+
+func (j *JwtTokenizer) RevokeAccessTokens(ids ...string) error {
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if err := j.Denylist.Add(context.Background(), id, AccessTokenTTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *JwtTokenizer) JWKS() JWKSet {
+	return j.Keys.JWKS()
 }
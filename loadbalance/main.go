@@ -105,6 +105,9 @@ func main() {
 		}
 
 		reverseProxy := httputil.NewSingleHostReverseProxy(target)
+		// flush every write right away, so long-lived streams such as
+		// /posts/stream reach the client without being buffered
+		reverseProxy.FlushInterval = -1
 		c.Request.URL.Path = c.Param("path")
 
 		println(c.Request.URL.Path)